@@ -0,0 +1,502 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// 结构化配置，可通过 LoadConfigFromEnv 或 LoadConfigFile 加载
+type Config struct {
+	Host      string            // 主机，默认 127.0.0.1
+	Port      int               // 端口，默认 3306
+	User      string            // 用户名
+	Password  string            // 密码
+	Database  string            // 数据库名
+	Charset   string            // 字符集，如 utf8mb4
+	Collation string            // 排序规则，如 utf8mb4_general_ci
+	Loc       string            // time.Time 的时区，如 Local、Asia/Shanghai
+	ParseTime bool              // 是否将 DATE/DATETIME 解析为 time.Time
+	TLS       string            // TLS配置：true、false、skip-verify、preferred 或 mysql.RegisterTLSConfig 注册的名称
+	Params    map[string]string // 其他驱动参数
+
+	Timeout      time.Duration // 建立连接超时
+	ReadTimeout  time.Duration // 读超时
+	WriteTimeout time.Duration // 写超时
+	PingTimeout  time.Duration // 初始化时 ping 的超时
+	QueryTimeout time.Duration // 默认语句超时
+	KillOnCancel bool          // ctx 取消或超时时 KILL QUERY
+
+	HealthCheckInterval time.Duration // 健康检查间隔
+	SlowThreshold       time.Duration // 慢查询阈值
+
+	MaxOpenConns    int           // 最大连接数，默认1000
+	MaxIdleConns    int           // 最大空闲连接数，默认200
+	ConnMaxLifetime time.Duration // 连接最长存活时间
+	ConnMaxIdleTime time.Duration // 连接最长空闲时间
+
+	Replicas             []ReplicaConfig // 从库，配置后读操作路由至从库
+	Balancer             Balancer        // 从库负载均衡策略，配置项取值 roundRobin(默认)、weighted
+	ReplicaCheckInterval time.Duration   // 从库健康检查间隔
+
+	LogLevel            LogLevel      // 最低日志级别，配置项取值 debug、info(默认)、warn、error
+	DisableStatementLog bool          // 不记录执行成功的语句
+	DisableMetrics      bool          // 不统计语句耗时及错误
+	SensitiveColumns    []string      // 敏感字段名模式，配置项以逗号分隔
+	RetryMaxAttempts    int           // 最大执行次数(含首次)，默认不重试
+	RetryBaseDelay      time.Duration // 首次重试前的等待
+	RetryMaxDelay       time.Duration // 重试最长等待
+}
+
+// 从库地址，用户名、密码、数据库及驱动参数与主库相同
+// 配置项以逗号分隔，格式为 host:port*weight，如 10.0.0.2:3306,10.0.0.3:3306*2，port 及 weight 可省略
+type ReplicaConfig struct {
+	Host   string
+	Port   int // 默认 3306
+	Weight int // 权重，仅 BalanceWeighted 有效
+}
+
+// 配置项：文件中的键名及对应的环境变量后缀
+var configKeys = []struct {
+	key string
+	env string
+}{
+	{"host", "HOST"},
+	{"port", "PORT"},
+	{"user", "USER"},
+	{"password", "PASSWORD"},
+	{"database", "DATABASE"},
+	{"charset", "CHARSET"},
+	{"collation", "COLLATION"},
+	{"loc", "LOC"},
+	{"parseTime", "PARSE_TIME"},
+	{"tls", "TLS"},
+	{"params", "PARAMS"},
+	{"timeout", "TIMEOUT"},
+	{"readTimeout", "READ_TIMEOUT"},
+	{"writeTimeout", "WRITE_TIMEOUT"},
+	{"pingTimeout", "PING_TIMEOUT"},
+	{"queryTimeout", "QUERY_TIMEOUT"},
+	{"killOnCancel", "KILL_ON_CANCEL"},
+	{"healthCheckInterval", "HEALTH_CHECK_INTERVAL"},
+	{"slowThreshold", "SLOW_THRESHOLD"},
+	{"maxOpenConns", "MAX_OPEN_CONNS"},
+	{"maxIdleConns", "MAX_IDLE_CONNS"},
+	{"connMaxLifetime", "CONN_MAX_LIFETIME"},
+	{"connMaxIdleTime", "CONN_MAX_IDLE_TIME"},
+	{"replicas", "REPLICAS"},
+	{"balancer", "BALANCER"},
+	{"replicaCheckInterval", "REPLICA_CHECK_INTERVAL"},
+	{"logLevel", "LOG_LEVEL"},
+	{"disableStatementLog", "DISABLE_STATEMENT_LOG"},
+	{"disableMetrics", "DISABLE_METRICS"},
+	{"sensitiveColumns", "SENSITIVE_COLUMNS"},
+	{"retryMaxAttempts", "RETRY_MAX_ATTEMPTS"},
+	{"retryBaseDelay", "RETRY_BASE_DELAY"},
+	{"retryMaxDelay", "RETRY_MAX_DELAY"},
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 基于结构化配置初始化默认实例
+func InitWithConfig(cfg Config) error {
+	_, err := RegisterWithConfig(DefaultInstance, cfg)
+	return err
+}
+
+// 基于结构化配置注册命名实例
+func RegisterWithConfig(name string, cfg Config) (*Instance, error) {
+	dataSource, err := cfg.FormatDSN()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return Register(name, dataSource, opts)
+}
+
+// 从环境变量加载配置，如 prefix 为 MYSQL 时读取 MYSQL_HOST、MYSQL_PORT、MYSQL_PARSE_TIME 等
+// 其中 MYSQL_PARAMS 的格式为 key1=value1&key2=value2，时长的格式为 5s、1m 等
+func LoadConfigFromEnv(prefix string) (Config, error) {
+	values := make(map[string]string)
+	for _, item := range configKeys {
+		if value, ok := os.LookupEnv(prefix + "_" + item.env); ok {
+			values[item.key] = value
+		}
+	}
+
+	cfg := Config{}
+	if params, ok := values["params"]; ok {
+		query, err := url.ParseQuery(params)
+		if err != nil {
+			return cfg, fmt.Errorf("mysql: config params: %v", err)
+		}
+		cfg.Params = make(map[string]string, len(query))
+		for key := range query {
+			cfg.Params[key] = query.Get(key)
+		}
+		delete(values, "params")
+	}
+
+	err := cfg.apply(values)
+	return cfg, err
+}
+
+// 从 JSON 或 YAML 文件加载配置(根据扩展名 .json/.yaml/.yml 判断)，键名同 configKeys，时长的格式为 5s、1m 等
+// replicas、sensitiveColumns 可以是列表或以逗号分隔的字符串
+func LoadConfigFile(path string) (Config, error) {
+	cfg := Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	// 数字保留原文，避免经 float64 转换后变为 1e+06、1.5 等
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err == nil {
+			// 空文件为 nil
+			if value := yamlValue(&node); value != nil {
+				var ok bool
+				if raw, ok = value.(map[string]interface{}); !ok {
+					err = fmt.Errorf("mysql: config file must be a map: %s", path)
+				}
+			}
+		}
+	default:
+		return cfg, fmt.Errorf("mysql: unsupported config file: %s", path)
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				s, err := configScalar(item)
+				if err != nil {
+					return cfg, fmt.Errorf("mysql: config %s: %v", key, err)
+				}
+				items = append(items, s)
+			}
+			values[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			if key != "params" {
+				return cfg, fmt.Errorf("mysql: config %s: unexpected map", key)
+			}
+			cfg.Params = make(map[string]string, len(v))
+			for k, item := range v {
+				s, err := configScalar(item)
+				if err != nil {
+					return cfg, fmt.Errorf("mysql: config params.%s: %v", k, err)
+				}
+				cfg.Params[k] = s
+			}
+		case nil:
+			// 空值视为未配置
+		default:
+			if key == "params" {
+				return cfg, fmt.Errorf("mysql: config params must be a map")
+			}
+			if values[key], err = configScalar(v); err != nil {
+				return cfg, fmt.Errorf("mysql: config %s: %v", key, err)
+			}
+		}
+	}
+
+	err = cfg.apply(values)
+	return cfg, err
+}
+
+// 构建主库的 DSN
+func (c Config) FormatDSN() (string, error) {
+	host, port := c.Host, c.Port
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == 0 {
+		port = 3306
+	}
+
+	dc := mysqldriver.NewConfig()
+	dc.Net = "tcp"
+	dc.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	dc.User = c.User
+	dc.Passwd = c.Password
+	dc.DBName = c.Database
+	dc.Collation = c.Collation
+	dc.ParseTime = c.ParseTime
+	dc.TLSConfig = c.TLS
+	dc.Timeout = c.Timeout
+	dc.ReadTimeout = c.ReadTimeout
+	dc.WriteTimeout = c.WriteTimeout
+
+	if c.Loc != "" {
+		loc, err := time.LoadLocation(c.Loc)
+		if err != nil {
+			return "", err
+		}
+		dc.Loc = loc
+	}
+
+	if len(c.Params) > 0 || c.Charset != "" {
+		dc.Params = make(map[string]string, len(c.Params)+1)
+		for key, value := range c.Params {
+			dc.Params[key] = value
+		}
+		if c.Charset != "" {
+			dc.Params["charset"] = c.Charset
+		}
+	}
+
+	return dc.FormatDSN(), nil
+}
+
+// 转换为实例选项，包括从库的 DSN
+func (c Config) Options() (*Options, error) {
+	opts := &Options{
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		PingTimeout:     c.PingTimeout,
+		QueryTimeout:    c.QueryTimeout,
+		KillOnCancel:    c.KillOnCancel,
+
+		HealthCheckInterval: c.HealthCheckInterval,
+		SlowThreshold:       c.SlowThreshold,
+
+		Balancer:             c.Balancer,
+		ReplicaCheckInterval: c.ReplicaCheckInterval,
+
+		LogLevel:            c.LogLevel,
+		DisableStatementLog: c.DisableStatementLog,
+		DisableMetrics:      c.DisableMetrics,
+		SensitiveColumns:    c.SensitiveColumns,
+		Retry: RetryPolicy{
+			MaxAttempts: c.RetryMaxAttempts,
+			BaseDelay:   c.RetryBaseDelay,
+			MaxDelay:    c.RetryMaxDelay,
+		},
+	}
+
+	for _, r := range c.Replicas {
+		replica := c
+		replica.Host, replica.Port = r.Host, r.Port
+		dataSource, err := replica.FormatDSN()
+		if err != nil {
+			return nil, err
+		}
+		opts.Replicas = append(opts.Replicas, Replica{DataSource: dataSource, Weight: r.Weight})
+	}
+	return opts, nil
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 将字符串形式的配置项写入配置
+func (c *Config) apply(values map[string]string) error {
+	var err error
+	for key, value := range values {
+		switch key {
+		case "host":
+			c.Host = value
+		case "port":
+			c.Port, err = strconv.Atoi(value)
+		case "user":
+			c.User = value
+		case "password":
+			c.Password = value
+		case "database":
+			c.Database = value
+		case "charset":
+			c.Charset = value
+		case "collation":
+			c.Collation = value
+		case "loc":
+			c.Loc = value
+		case "parseTime":
+			c.ParseTime, err = strconv.ParseBool(value)
+		case "tls":
+			c.TLS = value
+		case "timeout":
+			c.Timeout, err = time.ParseDuration(value)
+		case "readTimeout":
+			c.ReadTimeout, err = time.ParseDuration(value)
+		case "writeTimeout":
+			c.WriteTimeout, err = time.ParseDuration(value)
+		case "pingTimeout":
+			c.PingTimeout, err = time.ParseDuration(value)
+		case "queryTimeout":
+			c.QueryTimeout, err = time.ParseDuration(value)
+		case "killOnCancel":
+			c.KillOnCancel, err = strconv.ParseBool(value)
+		case "healthCheckInterval":
+			c.HealthCheckInterval, err = time.ParseDuration(value)
+		case "slowThreshold":
+			c.SlowThreshold, err = time.ParseDuration(value)
+		case "maxOpenConns":
+			c.MaxOpenConns, err = strconv.Atoi(value)
+		case "maxIdleConns":
+			c.MaxIdleConns, err = strconv.Atoi(value)
+		case "connMaxLifetime":
+			c.ConnMaxLifetime, err = time.ParseDuration(value)
+		case "connMaxIdleTime":
+			c.ConnMaxIdleTime, err = time.ParseDuration(value)
+		case "replicas":
+			c.Replicas, err = parseReplicas(value)
+		case "balancer":
+			c.Balancer, err = parseBalancer(value)
+		case "replicaCheckInterval":
+			c.ReplicaCheckInterval, err = time.ParseDuration(value)
+		case "logLevel":
+			c.LogLevel, err = parseLogLevel(value)
+		case "disableStatementLog":
+			c.DisableStatementLog, err = strconv.ParseBool(value)
+		case "disableMetrics":
+			c.DisableMetrics, err = strconv.ParseBool(value)
+		case "sensitiveColumns":
+			c.SensitiveColumns = splitList(value)
+		case "retryMaxAttempts":
+			c.RetryMaxAttempts, err = strconv.Atoi(value)
+		case "retryBaseDelay":
+			c.RetryBaseDelay, err = time.ParseDuration(value)
+		case "retryMaxDelay":
+			c.RetryMaxDelay, err = time.ParseDuration(value)
+		default:
+			return fmt.Errorf("mysql: unknown config key: %s", key)
+		}
+		if err != nil {
+			return fmt.Errorf("mysql: config %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// 解析从库列表，如 10.0.0.2:3306,10.0.0.3:3306*2
+func parseReplicas(value string) ([]ReplicaConfig, error) {
+	var replicas []ReplicaConfig
+	for _, item := range splitList(value) {
+		addr, weight, hasWeight := strings.Cut(item, "*")
+		r := ReplicaConfig{Host: addr}
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			r.Host = host
+			if r.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("replica %s: invalid port", item)
+			}
+		}
+		if hasWeight {
+			var err error
+			if r.Weight, err = strconv.Atoi(weight); err != nil {
+				return nil, fmt.Errorf("replica %s: invalid weight", item)
+			}
+		}
+		if r.Host == "" {
+			return nil, fmt.Errorf("replica %s: empty host", item)
+		}
+		replicas = append(replicas, r)
+	}
+	return replicas, nil
+}
+
+func parseBalancer(value string) (Balancer, error) {
+	switch strings.ToLower(value) {
+	case "", "roundrobin":
+		return BalanceRoundRobin, nil
+	case "weighted":
+		return BalanceWeighted, nil
+	}
+	return 0, fmt.Errorf("unknown balancer %s", value)
+}
+
+func parseLogLevel(value string) (LogLevel, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %s", value)
+}
+
+// 配置文件中的标量转换为字符串，数字为文件中的原文
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unexpected value %v", value)
+}
+
+// YAML 节点转换为 configScalar 可处理的值，标量均保留原文
+func yamlValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			return yamlValue(node.Content[0])
+		}
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		return node.Value
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			items = append(items, yamlValue(item))
+		}
+		return items
+	case yaml.MappingNode:
+		items := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			items[node.Content[i].Value] = yamlValue(node.Content[i+1])
+		}
+		return items
+	}
+	return nil
+}
+
+// 以逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
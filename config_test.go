@@ -0,0 +1,178 @@
+package mysql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigReplicas(t *testing.T) {
+	want := Config{
+		Host:     "10.0.0.1",
+		User:     "root",
+		Password: "123456",
+		Database: "ddy",
+		Replicas: []ReplicaConfig{
+			{Host: "10.0.0.2", Port: 3306},
+			{Host: "10.0.0.3", Port: 3307, Weight: 2},
+			{Host: "replica.local"},
+		},
+		Balancer:         BalanceWeighted,
+		SensitiveColumns: []string{"*password*", "*token*"},
+		RetryMaxAttempts: 3,
+		RetryBaseDelay:   100 * time.Millisecond,
+	}
+
+	t.Run("env", func(t *testing.T) {
+		env := map[string]string{
+			"HOST":               "10.0.0.1",
+			"USER":               "root",
+			"PASSWORD":           "123456",
+			"DATABASE":           "ddy",
+			"REPLICAS":           "10.0.0.2:3306, 10.0.0.3:3307*2,replica.local",
+			"BALANCER":           "weighted",
+			"SENSITIVE_COLUMNS":  "*password*,*token*",
+			"RETRY_MAX_ATTEMPTS": "3",
+			"RETRY_BASE_DELAY":   "100ms",
+		}
+		for key, value := range env {
+			t.Setenv("MYSQL_TEST_"+key, value)
+		}
+
+		cfg, err := LoadConfigFromEnv("MYSQL_TEST")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("got %+v, want %+v", cfg, want)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mysql.yaml")
+		data := `
+host: 10.0.0.1
+user: root
+password: "123456"
+database: ddy
+replicas:
+  - 10.0.0.2:3306
+  - 10.0.0.3:3307*2
+  - replica.local
+balancer: weighted
+sensitiveColumns: [ "*password*", "*token*" ]
+retryMaxAttempts: 3
+retryBaseDelay: 100ms
+`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := LoadConfigFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("got %+v, want %+v", cfg, want)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		opts, err := want.Options()
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas := []Replica{
+			{DataSource: "root:123456@tcp(10.0.0.2:3306)/ddy"},
+			{DataSource: "root:123456@tcp(10.0.0.3:3307)/ddy", Weight: 2},
+			{DataSource: "root:123456@tcp(replica.local:3306)/ddy"},
+		}
+		if !reflect.DeepEqual(opts.Replicas, replicas) {
+			t.Errorf("replicas = %+v, want %+v", opts.Replicas, replicas)
+		}
+		if opts.Balancer != BalanceWeighted || opts.Retry.MaxAttempts != 3 || opts.Retry.BaseDelay != 100*time.Millisecond {
+			t.Errorf("got balancer=%v retry=%+v", opts.Balancer, opts.Retry)
+		}
+		if !reflect.DeepEqual(opts.SensitiveColumns, want.SensitiveColumns) {
+			t.Errorf("sensitive columns = %q", opts.SensitiveColumns)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{"10.0.0.2:abc", "10.0.0.2:3306*x", ":3306"} {
+			if _, err := parseReplicas(value); err == nil {
+				t.Errorf("parseReplicas(%q) should fail", value)
+			}
+		}
+		if _, err := parseBalancer("random"); err == nil {
+			t.Error("parseBalancer(random) should fail")
+		}
+	})
+}
+
+func TestLoadConfigFileScalars(t *testing.T) {
+	want := Config{
+		Port:          3307,
+		Password:      "1.50",
+		ParseTime:     true,
+		Params:        map[string]string{"maxAllowedPacket": "1048576", "multiStatements": "false"},
+		MaxOpenConns:  1000000,
+		SlowThreshold: 200 * time.Millisecond,
+		Replicas:      []ReplicaConfig{{Host: "10.0.0.2", Port: 3306}},
+	}
+
+	files := map[string]string{
+		"mysql.json": `{
+	"port": 3307,
+	"password": 1.50,
+	"database": null,
+	"parseTime": true,
+	"params": {"maxAllowedPacket": 1048576, "multiStatements": false},
+	"maxOpenConns": 1000000,
+	"slowThreshold": "200ms",
+	"replicas": ["10.0.0.2:3306"]
+}`,
+		"mysql.yaml": `
+port: 3307
+password: 1.50
+database:
+parseTime: true
+params:
+  maxAllowedPacket: 1048576
+  multiStatements: false
+maxOpenConns: 1000000
+slowThreshold: 200ms
+replicas: &replicas
+  - 10.0.0.2:3306
+`,
+	}
+
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfigFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("got %+v, want %+v", cfg, want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mysql.json")
+		if err := os.WriteFile(path, []byte(`{"port": {"value": 3306}}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfigFile(path); err == nil {
+			t.Error("map value should fail")
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 记录语句的测试驱动，不连接数据库：语句均执行成功，影响 1 行，查询返回一行 1，含 LIMIT 0 的查询不返回数据
+type fakeDriver struct{}
+
+type fakeConn struct {
+	rec *fakeRecorder
+	id  int
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+type fakeRows struct {
+	done bool
+}
+
+type fakeResult struct{}
+
+// 驱动收到的语句
+type fakeStatement struct {
+	query string
+	args  []interface{}
+	conn  int // 执行语句的连接，从 1 开始编号
+}
+
+// 按 DSN 区分的语句记录
+type fakeRecorder struct {
+	mu         sync.Mutex
+	statements []fakeStatement
+	txs        []fakeStatement // 事务的开始、提交及回滚，query 为 BEGIN、COMMIT 或 ROLLBACK
+	conns      int
+	fail       func(query string) error // 返回非 nil 时语句执行失败，失败的语句同样会记录；ping 时 query 为 PING，不记录
+}
+
+var (
+	fakeRecorders sync.Map // dsn -> *fakeRecorder
+	fakeSeq       int64
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 创建使用测试驱动的实例，测试结束时关闭
+func newFakeInstance(t *testing.T, opts *Options) (*Instance, *fakeRecorder) {
+	t.Helper()
+
+	db, rec := newFakeDB(t)
+	if opts == nil {
+		opts = &Options{}
+	}
+	return newInstance(t.Name(), db, opts), rec
+}
+
+// 创建使用测试驱动的连接池，测试结束时关闭
+func newFakeDB(t *testing.T) (*sql.DB, *fakeRecorder) {
+	t.Helper()
+
+	rec := &fakeRecorder{}
+	dsn := fmt.Sprintf("%s#%d", t.Name(), atomic.AddInt64(&fakeSeq, 1))
+	fakeRecorders.Store(dsn, rec)
+	connector, err := newConnector(fakeDriver{}, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		_ = db.Close()
+		fakeRecorders.Delete(dsn)
+	})
+	return db, rec
+}
+
+// 将实例设为默认实例，测试结束时恢复
+func setDefault(t *testing.T, inst *Instance) {
+	t.Helper()
+
+	dbMutex.Lock()
+	old, ok := instances[DefaultInstance]
+	instances[DefaultInstance] = inst
+	dbMutex.Unlock()
+
+	t.Cleanup(func() {
+		dbMutex.Lock()
+		defer dbMutex.Unlock()
+
+		if ok {
+			instances[DefaultInstance] = old
+		} else {
+			delete(instances, DefaultInstance)
+		}
+	})
+}
+
+// 已执行的语句，不含事务的开始、提交及回滚
+func (r *fakeRecorder) Statements() []fakeStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]fakeStatement(nil), r.statements...)
+}
+
+// 事务的开始、提交及回滚
+func (r *fakeRecorder) Txs() []fakeStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]fakeStatement(nil), r.txs...)
+}
+
+// 指定语句的执行结果
+func (r *fakeRecorder) failWith(fail func(query string) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fail = fail
+}
+
+// 记录语句并返回执行结果，fail 可阻塞以模拟执行中的语句
+func (r *fakeRecorder) record(conn int, query string, args []driver.NamedValue) error {
+	r.mu.Lock()
+	st := fakeStatement{query: query, conn: conn}
+	for _, arg := range args {
+		st.args = append(st.args, arg.Value)
+	}
+	r.statements = append(r.statements, st)
+	fail := r.fail
+	r.mu.Unlock()
+
+	if fail != nil {
+		return fail(query)
+	}
+	return nil
+}
+
+func (r *fakeRecorder) recordTx(conn int, query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.txs = append(r.txs, fakeStatement{query: query, conn: conn})
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	rec, ok := fakeRecorders.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("fake: unknown dsn %q", dsn)
+	}
+	r := rec.(*fakeRecorder)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.conns++
+	return &fakeConn{rec: r, id: r.conns}, nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake: prepare is not supported")
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.rec.mu.Lock()
+	fail := c.rec.fail
+	c.rec.mu.Unlock()
+
+	if fail != nil {
+		return fail("PING")
+	}
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.rec.recordTx(c.id, "BEGIN")
+	return fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.rec.record(c.id, query, args); err != nil {
+		return nil, err
+	}
+	return fakeResult{}, nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "CONNECTION_ID()") {
+		if err := c.rec.record(c.id, query, args); err != nil {
+			return nil, err
+		}
+	}
+	return &fakeRows{done: strings.Contains(query, "LIMIT 0")}, nil
+}
+
+func (tx fakeTx) Commit() error {
+	tx.conn.rec.recordTx(tx.conn.id, "COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.conn.rec.recordTx(tx.conn.id, "ROLLBACK")
+	return nil
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"1"}
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func (fakeResult) LastInsertId() (int64, error) {
+	return 1, nil
+}
+
+func (fakeResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
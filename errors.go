@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// MySQL 错误码
+const (
+	errNumNotNull         = 1048 // 字段不能为 NULL
+	errNumDuplicateKey    = 1062 // 唯一键冲突
+	errNumLockWaitTimeout = 1205 // 锁等待超时
+	errNumDeadlock        = 1213 // 死锁
+	errNumDataTooLong     = 1406 // 数据过长
+	errNumRowIsReferenced = 1451 // 删除或更新被外键引用的行
+	errNumNoReferencedRow = 1452 // 外键引用的行不存在
+	errNumServerGone      = 2006 // MySQL server has gone away
+	errNumServerLost      = 2013 // Lost connection to MySQL server during query
+)
+
+var errNumSentinels = map[uint16]error{
+	errNumNotNull:         ErrNotNullViolation,
+	errNumDuplicateKey:    ErrDuplicateKey,
+	errNumLockWaitTimeout: ErrLockWaitTimeout,
+	errNumDeadlock:        ErrDeadlock,
+	errNumDataTooLong:     ErrDataTooLong,
+	errNumRowIsReferenced: ErrForeignKeyViolation,
+	errNumNoReferencedRow: ErrForeignKeyViolation,
+	errNumServerGone:      ErrConnectionLost,
+	errNumServerLost:      ErrConnectionLost,
+}
+
+// Duplicate entry 'sam' for key 'user.idx_username'
+var duplicateKeyRegexp = regexp.MustCompile(`for key '([^']+)'`)
+
+// sql: Scan error on column index 2, name "Comment": converting NULL to string is unsupported
+var scanErrorRegexp = regexp.MustCompile(`Scan error on column index (\d+)`)
+
+// 携带操作类型、表、语句指纹及字段的错误，可通过 errors.As 获取，errors.Is 仍可判断底层错误(如 sql.ErrNoRows)
+type Error struct {
+	Op        string // 操作类型：select、insert、update、delete、batch_insert、exec，无法确定语句的 Load 为 load
+	Table     string // 表名，原生SQL时为空
+	Statement string // 语句指纹，见 Fingerprint
+	Column    string // 出错的列，无法确定时为空
+	Field     string // 出错的结构体字段，如 User.Comment，无法确定时为空
+	Err       error
+}
+
+// 关联了 sentinel 的错误，Error 及 Unwrap 与原错误一致
+type codeError struct {
+	err      error
+	sentinel error
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 唯一键冲突时返回冲突的索引名(不含表名前缀)，如 idx_username
+func DuplicateKeyName(err error) (string, bool) {
+	var myErr *mysqldriver.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != errNumDuplicateKey {
+		return "", false
+	}
+
+	match := duplicateKeyRegexp.FindStringSubmatch(myErr.Message)
+	if match == nil {
+		return "", false
+	}
+
+	key := match[1]
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return key, true
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("mysql: ")
+	b.WriteString(e.Op)
+	if e.Table != "" {
+		b.WriteString(" ")
+		b.WriteString(e.Table)
+	}
+	if e.Column != "" {
+		b.WriteString(", column ")
+		b.WriteString(e.Column)
+	}
+	if e.Field != "" {
+		b.WriteString(" (field ")
+		b.WriteString(e.Field)
+		b.WriteString(")")
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	if e.Statement != "" {
+		b.WriteString(" [")
+		b.WriteString(e.Statement)
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+func (e *codeError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// 为驱动返回的错误关联 sentinel，无对应 sentinel 时原样返回
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sentinel error
+	var myErr *mysqldriver.MySQLError
+	switch {
+	case errors.As(err, &myErr):
+		sentinel = errNumSentinels[myErr.Number]
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysqldriver.ErrInvalidConn):
+		sentinel = ErrConnectionLost
+	}
+
+	if sentinel == nil {
+		return err
+	}
+	return &codeError{err: err, sentinel: sentinel}
+}
+
+// 为错误附加语句信息，st 为空时表示语句未知
+func wrapError(st *statement, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+
+	if st == nil {
+		return &Error{Op: opLoad, Err: err}
+	}
+	return &Error{Op: st.op, Table: st.table, Statement: Fingerprint(st.sql), Err: err}
+}
+
+// 为 Load 中 rows.Scan 的错误附加出错的列及结构体字段，elem 为接收数据的值
+func wrapScanError(st *statement, columns []string, elem reflect.Value, err error) error {
+	match := scanErrorRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return wrapError(st, err)
+	}
+
+	e := wrapError(st, err).(*Error)
+	if index, _ := strconv.Atoi(match[1]); index < len(columns) {
+		e.Column = columns[index]
+		e.Field = structFieldName(elem.Type(), e.Column)
+	}
+	// 列信息已单独记录，使用 database/sql 包装前的错误
+	if cause := errors.Unwrap(err); cause != nil {
+		e.Err = cause
+	}
+	return e
+}
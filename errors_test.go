@@ -0,0 +1,217 @@
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+type loadUser struct {
+	Created time.Time `db:"1"`
+}
+
+func TestWrapError(t *testing.T) {
+	st := &statement{op: opUpdate, table: "user", sql: "UPDATE `user` SET `Name`='sam' WHERE ID = 1"}
+	cause := errors.New("boom")
+
+	err := wrapError(st, cause)
+	var myErr *Error
+	if !errors.As(err, &myErr) || !errors.Is(err, cause) {
+		t.Fatalf("got %v, want *Error wrapping cause", err)
+	}
+	if want := "mysql: update user: boom [UPDATE `user` SET `Name`=? WHERE ID = ?]"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+	if wrapError(st, err) != err {
+		t.Error("*Error should not be wrapped twice")
+	}
+	if wrapError(st, nil) != nil {
+		t.Error("nil error should stay nil")
+	}
+
+	err = wrapError(nil, sql.ErrNoRows)
+	if !errors.Is(err, sql.ErrNoRows) || err.Error() != "mysql: load: "+sql.ErrNoRows.Error() {
+		t.Errorf("got %v, want load error wrapping sql.ErrNoRows", err)
+	}
+}
+
+func TestWrapScanError(t *testing.T) {
+	st := &statement{op: opSelect, table: "user", sql: "SELECT * FROM `user`"}
+	cause := errors.New("converting NULL to string is unsupported")
+	scanErr := &scanError{msg: `sql: Scan error on column index 1, name "Name": ` + cause.Error(), err: cause}
+
+	err := wrapScanError(st, []string{"ID", "Name"}, reflect.ValueOf(&struct {
+		ID   int64  `db:"ID"`
+		Nick string `db:"Name"`
+	}{}).Elem(), scanErr)
+
+	var myErr *Error
+	if !errors.As(err, &myErr) {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	if myErr.Column != "Name" || myErr.Field != "Nick" || myErr.Err != cause {
+		t.Errorf("got column=%q field=%q err=%v", myErr.Column, myErr.Field, myErr.Err)
+	}
+
+	// 非扫描错误只附加语句
+	err = wrapScanError(st, nil, reflect.Value{}, cause)
+	if !errors.As(err, &myErr) || myErr.Column != "" || !errors.Is(err, cause) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestLoadErrorContext(t *testing.T) {
+	inst, _ := newFakeInstance(t, &Options{DisableStatementLog: true})
+
+	t.Run("scan", func(t *testing.T) {
+		rows, err := inst.SelectWhere(Select("*").Form("user"), map[string]interface{}{"ID = ?": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var user loadUser
+		err = LoadStruct(rows, &user)
+
+		var myErr *Error
+		if !errors.As(err, &myErr) {
+			t.Fatalf("got %v, want *Error", err)
+		}
+		if myErr.Op != opSelect || myErr.Table != "user" || myErr.Statement == "" || myErr.Column != "1" || myErr.Field != "loadUser.Created" {
+			t.Errorf("got %+v", myErr)
+		}
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		rows, err := inst.SelectBySql("SELECT * FROM `user` LIMIT 0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var user loadUser
+		err = LoadStruct(rows, &user)
+
+		var myErr *Error
+		if !errors.Is(err, sql.ErrNoRows) || !errors.As(err, &myErr) || myErr.Op != opSelect {
+			t.Errorf("got %v, want select error wrapping sql.ErrNoRows", err)
+		}
+	})
+
+	if n := pendingCount(); n != 0 {
+		t.Errorf("got %d pending rows after load, want 0", n)
+	}
+}
+
+// database/sql 的扫描错误
+type scanError struct {
+	msg string
+	err error
+}
+
+func (e *scanError) Error() string {
+	return e.msg
+}
+
+func (e *scanError) Unwrap() error {
+	return e.err
+}
+
+func TestTranslateError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{err: &mysqldriver.MySQLError{Number: errNumNotNull}, want: ErrNotNullViolation},
+		{err: &mysqldriver.MySQLError{Number: errNumDuplicateKey}, want: ErrDuplicateKey},
+		{err: &mysqldriver.MySQLError{Number: errNumLockWaitTimeout}, want: ErrLockWaitTimeout},
+		{err: &mysqldriver.MySQLError{Number: errNumDeadlock}, want: ErrDeadlock},
+		{err: &mysqldriver.MySQLError{Number: errNumDataTooLong}, want: ErrDataTooLong},
+		{err: &mysqldriver.MySQLError{Number: errNumRowIsReferenced}, want: ErrForeignKeyViolation},
+		{err: &mysqldriver.MySQLError{Number: errNumNoReferencedRow}, want: ErrForeignKeyViolation},
+		{err: &mysqldriver.MySQLError{Number: errNumServerGone}, want: ErrConnectionLost},
+		{err: &mysqldriver.MySQLError{Number: errNumServerLost}, want: ErrConnectionLost},
+		{err: driver.ErrBadConn, want: ErrConnectionLost},
+		{err: mysqldriver.ErrInvalidConn, want: ErrConnectionLost},
+		{err: &mysqldriver.MySQLError{Number: 1064}},
+		{err: errors.New("boom")},
+	}
+
+	sentinels := []error{
+		ErrNotNullViolation, ErrDuplicateKey, ErrLockWaitTimeout, ErrDeadlock,
+		ErrDataTooLong, ErrForeignKeyViolation, ErrConnectionLost,
+	}
+	st := &statement{op: opInsert, table: "user", sql: "INSERT INTO `user` (`Name`) VALUES(?)"}
+
+	for _, c := range cases {
+		t.Run(c.err.Error(), func(t *testing.T) {
+			translated := translateError(c.err)
+			if c.want == nil && translated != c.err {
+				t.Errorf("got %v, want the error unchanged", translated)
+			}
+
+			// 经 codeError、redactedError 及 *Error 包装后仍可判断
+			wrapped := map[string]error{
+				"translated": translated,
+				"redacted":   &redactedError{msg: "redacted", err: translated},
+				"wrapped":    wrapError(st, translated),
+			}
+			for name, err := range wrapped {
+				if !errors.Is(err, c.err) {
+					t.Errorf("%s: errors.Is(%v) lost the cause", name, err)
+				}
+				for _, sentinel := range sentinels {
+					if got := errors.Is(err, sentinel); got != (sentinel == c.want) {
+						t.Errorf("%s: errors.Is(%v, %v) = %v", name, err, sentinel, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateKeyName(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		key  string
+		ok   bool
+	}{
+		{
+			name: "with table",
+			err:  &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry 'sam' for key 'user.idx_username'"},
+			key:  "idx_username",
+			ok:   true,
+		},
+		{
+			name: "without table",
+			err:  &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			key:  "PRIMARY",
+			ok:   true,
+		},
+		{
+			name: "wrapped",
+			err:  wrapError(&statement{op: opInsert}, translateError(&mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry 'sam' for key 'user.idx_username'"})),
+			key:  "idx_username",
+			ok:   true,
+		},
+		{
+			name: "redacted",
+			err:  &redactedError{msg: "redacted", err: &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry '***' for key 'user.idx_token'"}},
+			key:  "idx_token",
+			ok:   true,
+		},
+		{name: "other number", err: &mysqldriver.MySQLError{Number: errNumDeadlock, Message: "Deadlock found when trying to get lock for key 'x'"}},
+		{name: "not mysql", err: errors.New("Duplicate entry 'sam' for key 'user.idx_username'")},
+		{name: "nil"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if key, ok := DuplicateKeyName(c.err); key != c.key || ok != c.ok {
+				t.Errorf("got %q %v, want %q %v", key, ok, c.key, c.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	fingerprintList    = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)            // (?, ?, ?)
+	fingerprintRows    = regexp.MustCompile(`\(\?\+\)(\s*,\s*\(\?\+\))+`)          // (?+),(?+)
+	fingerprintTimeout = regexp.MustCompile(`(?i)MAX_EXECUTION_TIME\(\s*\d+\s*\)`) // 语句超时的提示
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 语句指纹：字符串、数字及十六进制字面量替换为 ?，合并空白，IN 列表及批量插入的多行值合并为 (?+)
+// 注释被去除，优化器提示 /*+ ... */ 保留，但去除其中的 MAX_EXECUTION_TIME(n)，使语句超时不影响指纹
+// 如 SELECT * FROM `user` WHERE `ID` IN (1, 2, 3) AND `Name`='sam' 的指纹为 SELECT * FROM `user` WHERE `ID` IN (?+) AND `Name`=?
+func Fingerprint(cmd string) string {
+	var b strings.Builder
+	b.Grow(len(cmd))
+
+	space := false
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '/' && i+1 < len(cmd) && cmd[i+1] == '*':
+			end := strings.Index(cmd[i+2:], "*/")
+			if end < 0 {
+				end = len(cmd)
+			} else {
+				end += i + 4
+			}
+			comment := cmd[i:end]
+			space = true
+			if strings.HasPrefix(comment, "/*+") {
+				hint := strings.Fields(fingerprintTimeout.ReplaceAllString(comment, ""))
+				if len(hint) > 0 && hint[0] == "/*+" {
+					hint = hint[1:]
+				}
+				if len(hint) > 0 && hint[len(hint)-1] == "*/" {
+					hint = hint[:len(hint)-1]
+				}
+				if len(hint) > 0 {
+					writeFingerprint(&b, &space, "/*+ "+strings.Join(hint, " ")+" */")
+					space = true
+				}
+			}
+			i = end - 1
+		case (c == 'x' || c == 'X' || c == 'b' || c == 'B') && i+1 < len(cmd) && cmd[i+1] == '\'' && (i == 0 || !isIdentChar(cmd[i-1])):
+			// X'FF'、b'01' 的前缀，由之后的引号替换为 ?
+		case c == '`':
+			j := i + 1
+			for j < len(cmd) {
+				if cmd[j] == '`' {
+					if j+1 < len(cmd) && cmd[j+1] == '`' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(cmd) {
+				j = len(cmd) - 1
+			}
+			writeFingerprint(&b, &space, cmd[i:j+1])
+			i = j
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(cmd) {
+				if cmd[j] == '\\' {
+					j += 2
+					continue
+				}
+				if cmd[j] == c {
+					if j+1 < len(cmd) && cmd[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			i = j
+			writeFingerprint(&b, &space, "?")
+		case isDigit(c) && (i == 0 || !isIdentChar(cmd[i-1])):
+			j := i
+			if c == '0' && i+1 < len(cmd) && (cmd[i+1] == 'x' || cmd[i+1] == 'X' || cmd[i+1] == 'b' || cmd[i+1] == 'B') {
+				// 0xFF、0b01
+				for j += 2; j < len(cmd) && isHexDigit(cmd[j]); j++ {
+				}
+			} else {
+				for j < len(cmd) && (isDigit(cmd[j]) || cmd[j] == '.') {
+					j++
+				}
+				// 1e5、1.5E-3
+				if j+1 < len(cmd) && (cmd[j] == 'e' || cmd[j] == 'E') {
+					k := j + 1
+					if cmd[k] == '+' || cmd[k] == '-' {
+						k++
+					}
+					if k < len(cmd) && isDigit(cmd[k]) {
+						for j = k; j < len(cmd) && isDigit(cmd[j]); j++ {
+						}
+					}
+				}
+			}
+			if j < len(cmd) && isIdentChar(cmd[j]) {
+				writeFingerprint(&b, &space, cmd[i:j])
+			} else {
+				writeFingerprint(&b, &space, "?")
+			}
+			i = j - 1
+		default:
+			writeFingerprint(&b, &space, string(c))
+		}
+	}
+
+	result := fingerprintList.ReplaceAllString(b.String(), "(?+)")
+	return fingerprintRows.ReplaceAllString(result, "(?+)")
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func writeFingerprint(b *strings.Builder, space *bool, s string) {
+	if *space && b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	*space = false
+	b.WriteString(s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
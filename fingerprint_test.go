@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "whitespace",
+			sql:  "SELECT *\n\tFROM  `user`   WHERE ID = ?",
+			want: "SELECT * FROM `user` WHERE ID = ?",
+		},
+		{
+			name: "quotes",
+			sql:  `SELECT * FROM user WHERE Name = 'sam' AND Nick = "tom" AND Memo = 'a\'b'`,
+			want: "SELECT * FROM user WHERE Name = ? AND Nick = ? AND Memo = ?",
+		},
+		{
+			name: "doubled quotes",
+			sql:  `SELECT * FROM user WHERE Name = 'it''s' AND Nick = "say ""hi"""`,
+			want: "SELECT * FROM user WHERE Name = ? AND Nick = ?",
+		},
+		{
+			name: "numbers",
+			sql:  "SELECT * FROM user2 WHERE Score > 1.5 AND Rate < 1e-3 AND t1.c2 = 10",
+			want: "SELECT * FROM user2 WHERE Score > ? AND Rate < ? AND t1.c2 = ?",
+		},
+		{
+			name: "hex literals",
+			sql:  "SELECT * FROM user WHERE Flag = 0xFF AND Mask = 0b101 AND Hash = X'0A1B' AND Bits = b'01'",
+			want: "SELECT * FROM user WHERE Flag = ? AND Mask = ? AND Hash = ? AND Bits = ?",
+		},
+		{
+			name: "in list",
+			sql:  "SELECT * FROM `user` WHERE `ID` IN (1, 2, 3) AND Name IN ('a','b') AND Age IN (?, ?)",
+			want: "SELECT * FROM `user` WHERE `ID` IN (?+) AND Name IN (?+) AND Age IN (?+)",
+		},
+		{
+			name: "multi-row values",
+			sql:  "INSERT INTO `user` (`Name`,`Age`) VALUES ('sam', 18), ('tom', 20),(?,?)",
+			want: "INSERT INTO `user` (`Name`,`Age`) VALUES (?+)",
+		},
+		{
+			name: "identifier with quote",
+			sql:  "SELECT `it's` FROM `user`",
+			want: "SELECT `it's` FROM `user`",
+		},
+		{
+			name: "max execution time",
+			sql:  "SELECT /*+ MAX_EXECUTION_TIME(500) */ * FROM `user` WHERE ID = 1",
+			want: "SELECT * FROM `user` WHERE ID = ?",
+		},
+		{
+			name: "other hints",
+			sql:  "SELECT /*+ MAX_EXECUTION_TIME(500)  INDEX(user idx_name) */ * FROM `user`",
+			want: "SELECT /*+ INDEX(user idx_name) */ * FROM `user`",
+		},
+		{
+			name: "comment",
+			sql:  "/* tenant 7 */ SELECT 1",
+			want: "SELECT ?",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Fingerprint(c.sql); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+
+	// 语句超时不影响指纹
+	if a, b := Fingerprint(addMaxExecutionTime("SELECT * FROM `user`", 500*time.Millisecond)), Fingerprint("SELECT * FROM `user`"); a != b {
+		t.Errorf("fingerprint with timeout %q, want %q", a, b)
+	}
+}
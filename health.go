@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"time"
+)
+
+// 健康状态
+type HealthState int
+
+const (
+	HealthUnknown HealthState = iota // 未检查
+	HealthUp                         // 正常
+	HealthDown                       // 异常
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second // 默认健康检查间隔
+)
+
+// 健康检查结果
+type HealthStatus struct {
+	State       HealthState
+	LastError   error         // 最近一次检查的错误，正常时为nil
+	Latency     time.Duration // 最近一次 ping 的耗时
+	LastCheck   time.Time     // 最近一次检查时间
+	LastSuccess time.Time     // 最近一次检查成功的时间
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthUp:
+		return "up"
+	case HealthDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+// 获取默认实例的健康状态
+func Health() HealthStatus {
+	return Default().Health()
+}
+
+// 获取实例的健康状态
+func (i *Instance) Health() HealthStatus {
+	i.healthMu.RLock()
+	defer i.healthMu.RUnlock()
+
+	return i.health
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 定时 ping 主库，连接失效时由 database/sql 在下一次 ping 时重新建立连接
+func (i *Instance) checkHealth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			start := time.Now()
+			err := pingTimeout(i.db, interval)
+			i.recordHealth(time.Since(start), err)
+		}
+	}
+}
+
+// 记录健康检查结果，状态变化时回调 Options.OnHealthChange
+func (i *Instance) recordHealth(latency time.Duration, err error) {
+	now := time.Now()
+
+	i.healthMu.Lock()
+	old := i.health.State
+	i.health.LastCheck = now
+	i.health.Latency = latency
+	i.health.LastError = err
+	if err == nil {
+		i.health.State = HealthUp
+		i.health.LastSuccess = now
+	} else {
+		i.health.State = HealthDown
+	}
+	status := i.health
+	i.healthMu.Unlock()
+
+	if old != status.State && i.opts.OnHealthChange != nil {
+		i.opts.OnHealthChange(i.name, old, status)
+	}
+}
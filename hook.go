@@ -0,0 +1,151 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 语句事件，在钩子间传递
+type QueryEvent struct {
+	Instance     string        // 实例名称
+	Operation    string        // 操作类型：select、insert、update、delete、batch_insert、exec、begin、commit、rollback
+	Table        string        // 表名，无法确定时为空
+	Statement    string        // SQL 语句，可在 BeforeQuery 中改写
+	Args         []interface{} // 参数，可在 BeforeQuery 中改写
+	InTx         bool          // 是否在事务内执行
+	Start        time.Time     // 开始时间
+	Duration     time.Duration // 执行耗时，AfterQuery 中有效
+	RowsAffected int64         // 影响行数，AfterQuery 中有效，查询语句为 -1
+	Err          error         // 执行错误，AfterQuery 中有效
+
+	sess *session
+	st   *statement
+}
+
+// 语句钩子，对所有语句(包括事务内语句及事务的开始、提交、回滚)生效
+// BeforeQuery 按注册顺序调用，返回的 ctx 传给后续钩子及语句执行，返回错误时不再执行语句并以该错误返回
+// AfterQuery 按注册的逆序调用，仅调用 BeforeQuery 已成功返回的钩子
+type Hook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error)
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// 全局钩子，对所有实例生效
+var (
+	globalHooksMu sync.RWMutex
+	globalHooks   []Hook
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 添加全局钩子，对所有实例生效(包括之后通过 Init/Register 注册或替换的实例)，在 Options.Hooks 之后调用
+func AddHook(hook Hook) {
+	globalHooksMu.Lock()
+	defer globalHooksMu.Unlock()
+
+	hooks := make([]Hook, 0, len(globalHooks)+1)
+	hooks = append(hooks, globalHooks...)
+	globalHooks = append(hooks, hook)
+}
+
+// 为实例添加钩子，在 Options.Hooks 及全局钩子之后调用
+func (i *Instance) AddHook(hook Hook) {
+	i.hooksMu.Lock()
+	defer i.hooksMu.Unlock()
+
+	hooks := make([]Hook, 0, len(i.hooks)+1)
+	hooks = append(hooks, i.hooks...)
+	i.hooks = append(hooks, hook)
+}
+
+// 敏感字段脱敏后的语句，用于记录日志
+func (e *QueryEvent) RedactedStatement() string {
+	return e.sess.redactSQL(e.st, e.Statement)
+}
+
+// 敏感字段脱敏后的参数，用于记录日志
+func (e *QueryEvent) RedactedArgs() []interface{} {
+	return e.sess.redactArgs(e.st, e.Args)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (s *session) hooks() []Hook {
+	globalHooksMu.RLock()
+	global := globalHooks
+	globalHooksMu.RUnlock()
+
+	if s.inst == nil {
+		return global
+	}
+
+	s.inst.hooksMu.RLock()
+	defer s.inst.hooksMu.RUnlock()
+
+	tracer := s.inst.opts.Tracer
+	if tracer == nil && len(global) == 0 && len(s.inst.hooks) == 0 {
+		return s.inst.opts.Hooks
+	}
+
+	hooks := make([]Hook, 0, len(s.inst.opts.Hooks)+len(global)+len(s.inst.hooks)+1)
+	if tracer != nil {
+		hooks = append(hooks, tracingHook{tracer: tracer})
+	}
+	hooks = append(hooks, s.inst.opts.Hooks...)
+	hooks = append(hooks, global...)
+	return append(hooks, s.inst.hooks...)
+}
+
+// 依次执行钩子后调用 run，run 返回影响行数，钩子改写的语句和参数会写回 st
+func (s *session) withHooks(ctx context.Context, st *statement, run func(ctx context.Context) (int64, error)) error {
+	hooks := s.hooks()
+	if len(hooks) == 0 {
+		_, err := run(ctx)
+		return err
+	}
+
+	event := &QueryEvent{
+		Operation:    st.op,
+		Table:        st.table,
+		Statement:    st.sql,
+		Args:         st.args,
+		InTx:         s.inTx(),
+		Start:        time.Now(),
+		RowsAffected: -1,
+		sess:         s,
+		st:           st,
+	}
+	if s.inst != nil {
+		event.Instance = s.inst.name
+	}
+
+	var err error
+	n := 0
+	for ; n < len(hooks); n++ {
+		var hookCtx context.Context
+		if hookCtx, err = hooks[n].BeforeQuery(ctx, event); err != nil {
+			break
+		}
+		if hookCtx != nil {
+			ctx = hookCtx
+		}
+	}
+
+	if err == nil {
+		if len(event.Args) != len(st.args) || (len(st.args) > 0 && &event.Args[0] != &st.args[0]) {
+			// 钩子替换了参数，原有的参数字段不再对应，按改写后的语句重新推断
+			st.columns = nil
+		}
+		st.sql, st.args = event.Statement, event.Args
+		event.RowsAffected, err = run(ctx)
+	}
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+
+	for n--; n >= 0; n-- {
+		hooks[n].AfterQuery(ctx, event)
+	}
+
+	return err
+}
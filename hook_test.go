@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 记录调用顺序的钩子
+type recordHook struct {
+	name    string
+	calls   *hookCalls
+	err     error                   // BeforeQuery 返回的错误
+	rewrite func(event *QueryEvent) // 在 BeforeQuery 中改写语句
+}
+
+type hookCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (h *recordHook) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	h.calls.add("before " + h.name + " " + event.Operation)
+	if h.rewrite != nil {
+		h.rewrite(event)
+	}
+	return ctx, h.err
+}
+
+func (h *recordHook) AfterQuery(_ context.Context, event *QueryEvent) {
+	h.calls.add("after " + h.name + " " + event.Operation)
+}
+
+func (c *hookCalls) add(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = append(c.calls, call)
+}
+
+func (c *hookCalls) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// 添加全局钩子，测试结束时恢复
+func addGlobalHook(t *testing.T, hook Hook) {
+	globalHooksMu.RLock()
+	old := globalHooks
+	globalHooksMu.RUnlock()
+
+	AddHook(hook)
+	t.Cleanup(func() {
+		globalHooksMu.Lock()
+		defer globalHooksMu.Unlock()
+
+		globalHooks = old
+	})
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func TestHookOrder(t *testing.T) {
+	calls := &hookCalls{}
+	addGlobalHook(t, &recordHook{name: "global", calls: calls})
+	inst, _ := newFakeInstance(t, &Options{
+		DisableStatementLog: true,
+		Hooks:               []Hook{&recordHook{name: "a", calls: calls}, &recordHook{name: "b", calls: calls}},
+	})
+	inst.AddHook(&recordHook{name: "instance", calls: calls})
+
+	if _, err := inst.Insert("user", map[string]interface{}{"Name": "sam"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"before a insert", "before b insert", "before global insert", "before instance insert",
+		"after instance insert", "after global insert", "after b insert", "after a insert",
+	}
+	if got := calls.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls %q, want %q", got, want)
+	}
+
+	// 事务的开始、提交同样经过钩子
+	if err := inst.WithTx(context.Background(), nil, func(*Tx) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, call := range calls.take() {
+		if strings.HasPrefix(call, "before") {
+			ops = append(ops, call)
+		}
+	}
+	if len(ops) != 8 || ops[0] != "before a begin" || ops[4] != "before a commit" {
+		t.Errorf("got tx calls %q", ops)
+	}
+}
+
+func TestHookRewrite(t *testing.T) {
+	var inTx []bool
+	hook := &recordHook{name: "rewrite", calls: &hookCalls{}, rewrite: func(event *QueryEvent) {
+		inTx = append(inTx, event.InTx)
+		if event.Operation == opUpdate {
+			event.Statement = "/* tenant */ " + event.Statement + " AND TenantID = ?"
+			event.Args = append(event.Args, 7)
+		}
+	}}
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true, Hooks: []Hook{hook}})
+
+	if _, err := inst.Update("user", map[string]interface{}{"Name": "sam"}, map[string]interface{}{"ID = ?": 1}); err != nil {
+		t.Fatal(err)
+	}
+	assertStatements(t, rec, "/* tenant */ UPDATE `user` SET `Name`=?  WHERE (ID = ?) AND TenantID = ?", []interface{}{"sam", int64(1), int64(7)})
+
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{false, false, true}; !reflect.DeepEqual(inTx, want) {
+		t.Errorf("got InTx %v, want %v", inTx, want)
+	}
+}
+
+func TestHookShortCircuit(t *testing.T) {
+	denied := errors.New("denied")
+	calls := &hookCalls{}
+	inst, rec := newFakeInstance(t, &Options{
+		DisableStatementLog: true,
+		Hooks: []Hook{
+			&recordHook{name: "a", calls: calls},
+			&recordHook{name: "deny", calls: calls, err: denied},
+			&recordHook{name: "c", calls: calls},
+		},
+	})
+
+	_, err := inst.Delete("user", map[string]interface{}{"ID = ?": 1})
+	var myErr *Error
+	if !errors.Is(err, denied) || !errors.As(err, &myErr) || myErr.Op != opDelete {
+		t.Fatalf("err = %v, want delete error wrapping denied", err)
+	}
+	assertStatements(t, rec, "", nil)
+
+	// 只有 BeforeQuery 成功的钩子调用 AfterQuery
+	want := []string{"before a delete", "before deny delete", "after a delete"}
+	if got := calls.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls %q, want %q", got, want)
+	}
+
+	// 拦截 BEGIN 时不开启事务
+	if _, err := inst.Begin(); !errors.Is(err, denied) {
+		t.Errorf("begin err = %v, want denied", err)
+	}
+	assertTxs(t, rec, nil)
+}
+
+func TestGlobalHook(t *testing.T) {
+	calls := &hookCalls{}
+	first, _ := newFakeInstance(t, &Options{DisableStatementLog: true})
+	addGlobalHook(t, &recordHook{name: "global", calls: calls})
+	// 添加全局钩子之后注册或替换的实例同样生效
+	second, _ := newFakeInstance(t, &Options{DisableStatementLog: true})
+
+	for _, inst := range []*Instance{first, second} {
+		if _, err := inst.Count("user", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"before global select", "after global select", "before global select", "after global select"}
+	if got := calls.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 实例：对应一个数据库连接池，提供与包级函数相同的操作
+type Instance struct {
+	session
+	name      string
+	opts      Options
+	db        *sql.DB    // 主库
+	replicas  []*replica // 从库
+	balancer  Balancer
+	counter   uint64 // 从库负载均衡计数
+	stop      chan struct{}
+	closeOnce sync.Once
+	healthMu  sync.RWMutex
+	health    HealthStatus
+	tracker   tracker
+	hooksMu   sync.RWMutex
+	hooks     []Hook // 通过 AddHook 添加的钩子
+	metrics   metrics
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func newInstance(name string, db *sql.DB, opts *Options) *Instance {
+	inst := &Instance{name: name, opts: *opts, db: db, stop: make(chan struct{})}
+	inst.session = session{exec: db, inst: inst}
+	return inst
+}
+
+// 实例名称
+func (i *Instance) Name() string {
+	return i.name
+}
+
+// 获取DB
+func (i *Instance) GetDB() *sql.DB {
+	return i.db
+}
+
+// 关闭连接池，包括从库
+func (i *Instance) Close() error {
+	if i.db == nil {
+		return nil
+	}
+
+	var err error
+	i.closeOnce.Do(func() {
+		close(i.stop)
+		for _, r := range i.replicas {
+			_ = r.db.Close()
+		}
+		err = i.db.Close()
+	})
+	return err
+}
+
+// 万能加载，同包级 Load
+func (i *Instance) Load(rows *sql.Rows, value interface{}) (int, error) {
+	return Load(rows, value)
+}
+
+// 开启事务
+func (i *Instance) Begin() (*Tx, error) {
+	return i.BeginTx(context.Background(), nil)
+}
+
+// 基于上下文和选项开启事务，若ctx中已携带事务(见 Tx.Context)，则开启基于保存点的嵌套事务
+func (i *Instance) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	if parent := i.parentTx(ctx); parent != nil {
+		return parent.Begin()
+	}
+
+	if i.db == nil {
+		return nil, errDBNotInit
+	}
+	if err := i.tracker.enter(false); err != nil {
+		return nil, err
+	}
+	defer i.tracker.leave()
+
+	var sqlOpts *sql.TxOptions
+	if opts != nil {
+		sqlOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	}
+
+	var span Span
+	if tracer := i.tracer(); tracer != nil {
+		ctx, span = tracer.Start(ctx, "transaction", Field{AttrDBSystem, "mysql"})
+	}
+
+	// 一致性快照须在开启事务前设置隔离级别，因此不使用 db.BeginTx，而是在独占连接上手动开启
+	var tx *sql.Tx
+	var conn *sql.Conn
+	var exec Executor
+	err := i.withHooks(ctx, &statement{op: opBegin, sql: "BEGIN"}, func(ctx context.Context) (int64, error) {
+		var err error
+		if opts != nil && opts.ConsistentSnapshot {
+			if conn, err = i.beginSnapshot(ctx, opts); err == nil {
+				exec = snapshotConn{conn: conn}
+			}
+		} else if tx, err = i.db.BeginTx(ctx, sqlOpts); err == nil {
+			exec = tx
+		}
+		return -1, err
+	})
+	if err != nil {
+		if span != nil {
+			endSpan(span, err)
+		}
+		return nil, err
+	}
+
+	t := &Tx{session: session{exec: exec, inst: i}, tx: tx, conn: conn, db: i.db, seq: new(int), span: span}
+	t.ctx = context.WithValue(ctx, txCtxKey{}, t)
+	if err := i.tracker.addTx(t); err != nil {
+		_ = t.finish(false)
+		t.endSpan(err)
+		return nil, err
+	}
+
+	if i.opts.KillOnCancel {
+		if t.connID, err = connectionID(ctx, exec); err != nil {
+			_ = t.Rollback()
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// 在事务中执行fn：fn返回nil时提交，返回错误或panic时回滚(panic会在回滚后重新抛出)
+// fn 中已自行提交时视为成功，已自行回滚时返回 sql.ErrTxDone
+// 若 opts.MaxAttempts > 1，遇到死锁或锁等待超时时会重新执行整个fn
+// 若ctx中已携带事务，则fn在保存点中执行，失败时仅回滚至该保存点，且不会重试(死锁会回滚整个最外层事务)
+func (i *Instance) WithTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+	attempts := 1
+	backoff := defaultTxBackoff
+	if opts != nil && i.parentTx(ctx) == nil {
+		if opts.MaxAttempts > 1 {
+			attempts = opts.MaxAttempts
+		}
+		if opts.Backoff != nil {
+			backoff = opts.Backoff
+		}
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = i.runTx(ctx, opts, fn); err == nil || !isTxRetryable(err) || attempt == attempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 打开从库并启动健康检查
+func (i *Instance) openReplicas(opts *Options) error {
+	if len(opts.Replicas) == 0 {
+		return nil
+	}
+
+	timeout := opts.PingTimeout
+	if timeout <= 0 {
+		timeout = defaultReplicaPingTimeout
+	}
+
+	for _, item := range opts.Replicas {
+		db, err := openDB(item.DataSource, opts)
+		if err != nil {
+			return err
+		}
+
+		r := &replica{db: db, weight: item.Weight}
+		if r.weight <= 0 {
+			r.weight = 1
+		}
+		r.setHealthy(pingTimeout(db, timeout) == nil)
+		i.replicas = append(i.replicas, r)
+	}
+
+	interval := opts.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+
+	i.balancer = opts.Balancer
+	i.reader = i.route
+	go i.checkReplicas(interval)
+
+	return nil
+}
+
+// 启动主库健康检查
+func (i *Instance) startHealthCheck() {
+	interval := i.opts.HealthCheckInterval
+	if interval < 0 {
+		return
+	}
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+	go i.checkHealth(interval)
+}
+
+// 获取ctx中携带的、属于当前实例的事务
+func (i *Instance) parentTx(ctx context.Context) *Tx {
+	if parent := TxFromContext(ctx); parent != nil && parent.db == i.db {
+		return parent
+	}
+	return nil
+}
+
+// 执行一次事务
+func (i *Instance) runTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) (err error) {
+	tx, err := i.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if tx.committed {
+		return nil
+	}
+
+	return tx.Commit()
+}
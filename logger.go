@@ -0,0 +1,145 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hzxgo/log"
+)
+
+// 日志级别，取值与 log/slog 一致
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+// 日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// 日志接口，可通过 Options.Logger 按实例配置，或通过 SetLogger 修改默认值
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...Field)
+}
+
+// 默认日志：github.com/hzxgo/log
+type hzxLogger struct{}
+
+// log/slog 适配
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// 包级默认日志
+var defaultLogger atomic.Value
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func init() {
+	defaultLogger.Store(loggerHolder{hzxLogger{}})
+}
+
+// atomic.Value 要求存储的类型一致
+type loggerHolder struct {
+	Logger
+}
+
+// 修改未指定 Options.Logger 的实例所使用的日志
+func SetLogger(logger Logger) {
+	defaultLogger.Store(loggerHolder{logger})
+}
+
+// 基于 log/slog 创建日志
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
+	}
+	l.logger.LogAttrs(ctx, slog.Level(level), msg, attrs...)
+}
+
+func (hzxLogger) Log(_ context.Context, level LogLevel, msg string, fields ...Field) {
+	items := make([]string, 0, len(fields))
+	for _, field := range fields {
+		items = append(items, fmt.Sprintf("%s=%+v", field.Key, field.Value))
+	}
+
+	line := msg
+	if len(items) > 0 {
+		line = fmt.Sprintf("%s | %s", msg, strings.Join(items, " "))
+	}
+
+	if level >= LevelWarn {
+		log.Errorf("[MySQL]: %s", line)
+	} else {
+		log.Infof("[MySQL]: %s", line)
+	}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 获取会话使用的日志
+func (s *session) logger() Logger {
+	if s.inst != nil && s.inst.opts.Logger != nil {
+		return s.inst.opts.Logger
+	}
+	return defaultLogger.Load().(loggerHolder).Logger
+}
+
+// 记录语句：成功时为 Info 级别(可通过 Options.DisableStatementLog 关闭)，失败时为 Error 级别
+// affected 为 -1 时表示查询语句，不记录影响行数
+func (s *session) logStatement(ctx context.Context, st *statement, duration time.Duration, affected int64, err error) {
+	level := LevelInfo
+	msg := "statement"
+	if err != nil {
+		level = LevelError
+		msg = "statement failed"
+	}
+
+	var minLevel LogLevel
+	if s.inst != nil {
+		if err == nil && s.inst.opts.DisableStatementLog {
+			return
+		}
+		minLevel = s.inst.opts.LogLevel
+	}
+	if level < minLevel {
+		return
+	}
+
+	fields := make([]Field, 0, 8)
+	if s.inst != nil {
+		fields = append(fields, Field{Key: "instance", Value: s.inst.name})
+	}
+	fields = append(fields, Field{Key: "operation", Value: st.op})
+	if st.table != "" {
+		fields = append(fields, Field{Key: "table", Value: st.table})
+	}
+	fields = append(fields, Field{Key: "statement", Value: s.redactSQL(st, st.sql)})
+	if len(st.args) > 0 {
+		fields = append(fields, Field{Key: "args", Value: s.redactArgs(st, st.args)})
+	}
+	fields = append(fields, Field{Key: "duration", Value: duration})
+	if affected >= 0 {
+		fields = append(fields, Field{Key: "rows_affected", Value: affected})
+	}
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err})
+	}
+
+	s.logger().Log(ctx, level, msg, fields...)
+}
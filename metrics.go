@@ -0,0 +1,246 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// 延迟直方图的桶上限(秒)，与 Prometheus 默认值一致
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// 实例指标，通过 Options.DisableMetrics 关闭
+type metrics struct {
+	mu        sync.Mutex
+	latencies map[latencyKey]*histogram
+	errors    map[errorKey]uint64
+}
+
+type latencyKey struct {
+	op    string
+	table string
+}
+
+type errorKey struct {
+	op     string
+	number uint16
+}
+
+type histogram struct {
+	counts []uint64 // 各桶计数(非累计)，最后一个为 +Inf
+	count  uint64
+	sum    time.Duration
+}
+
+// 指标快照
+type MetricsSnapshot struct {
+	Instance     string
+	Latencies    []LatencySnapshot
+	Errors       []ErrorCount
+	Pool         sql.DBStats   // 主库连接池
+	ReplicaPools []sql.DBStats // 从库连接池，顺序与 Options.Replicas 一致
+}
+
+// 按操作类型及表统计的延迟
+type LatencySnapshot struct {
+	Operation string
+	Table     string // 通过 SQL 执行的语句为空
+	Count     uint64
+	Sum       time.Duration
+	Buckets   []Bucket
+}
+
+// 直方图桶，Count 为耗时不超过 UpperBound 的累计次数
+type Bucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// 按操作类型及 MySQL 错误码统计的错误次数，Number 为 0 表示非 MySQL 返回的错误(如连接失败、超时)
+type ErrorCount struct {
+	Operation string
+	Number    uint16
+	Count     uint64
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 默认实例的指标快照
+func Metrics() MetricsSnapshot {
+	return Default().Metrics()
+}
+
+// 以 Prometheus 文本格式输出所有实例的指标
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dbMutex.RLock()
+		snapshots := make([]MetricsSnapshot, 0, len(instances))
+		for _, inst := range instances {
+			snapshots = append(snapshots, inst.Metrics())
+		}
+		dbMutex.RUnlock()
+
+		sort.Slice(snapshots, func(i, j int) bool {
+			return snapshots[i].Instance < snapshots[j].Instance
+		})
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writePrometheus(w, snapshots)
+	})
+}
+
+// 指标快照
+func (i *Instance) Metrics() MetricsSnapshot {
+	snapshot := MetricsSnapshot{Instance: i.name}
+	if i.db != nil {
+		snapshot.Pool = i.db.Stats()
+	}
+	for _, r := range i.replicas {
+		snapshot.ReplicaPools = append(snapshot.ReplicaPools, r.db.Stats())
+	}
+
+	i.metrics.mu.Lock()
+	defer i.metrics.mu.Unlock()
+
+	for key, h := range i.metrics.latencies {
+		latency := LatencySnapshot{Operation: key.op, Table: key.table, Count: h.count, Sum: h.sum}
+		var cumulative uint64
+		for n, bound := range latencyBuckets {
+			cumulative += h.counts[n]
+			latency.Buckets = append(latency.Buckets, Bucket{
+				UpperBound: time.Duration(bound * float64(time.Second)),
+				Count:      cumulative,
+			})
+		}
+		snapshot.Latencies = append(snapshot.Latencies, latency)
+	}
+	sort.Slice(snapshot.Latencies, func(a, b int) bool {
+		x, y := snapshot.Latencies[a], snapshot.Latencies[b]
+		return x.Operation < y.Operation || (x.Operation == y.Operation && x.Table < y.Table)
+	})
+
+	for key, count := range i.metrics.errors {
+		snapshot.Errors = append(snapshot.Errors, ErrorCount{Operation: key.op, Number: key.number, Count: count})
+	}
+	sort.Slice(snapshot.Errors, func(a, b int) bool {
+		x, y := snapshot.Errors[a], snapshot.Errors[b]
+		return x.Operation < y.Operation || (x.Operation == y.Operation && x.Number < y.Number)
+	})
+
+	return snapshot
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 记录语句耗时及错误
+func (s *session) observe(st *statement, duration time.Duration, err error) {
+	if s.inst == nil || s.inst.opts.DisableMetrics {
+		return
+	}
+
+	m := &s.inst.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.latencies == nil {
+		m.latencies = make(map[latencyKey]*histogram)
+		m.errors = make(map[errorKey]uint64)
+	}
+
+	key := latencyKey{op: st.op, table: st.table}
+	h, ok := m.latencies[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		m.latencies[key] = h
+	}
+	h.counts[sort.SearchFloat64s(latencyBuckets, duration.Seconds())]++
+	h.count++
+	h.sum += duration
+
+	if err != nil {
+		var number uint16
+		var myErr *mysqldriver.MySQLError
+		if errors.As(err, &myErr) {
+			number = myErr.Number
+		}
+		m.errors[errorKey{op: st.op, number: number}]++
+	}
+}
+
+func writePrometheus(w io.Writer, snapshots []MetricsSnapshot) {
+	fmt.Fprintln(w, "# HELP mysql_statement_duration_seconds Statement latency by operation and table.")
+	fmt.Fprintln(w, "# TYPE mysql_statement_duration_seconds histogram")
+	for _, snapshot := range snapshots {
+		for _, latency := range snapshot.Latencies {
+			labels := promLabels("instance", snapshot.Instance, "operation", latency.Operation, "table", latency.Table)
+			for _, bucket := range latency.Buckets {
+				fmt.Fprintf(w, "mysql_statement_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+					labels, promFloat(bucket.UpperBound.Seconds()), bucket.Count)
+			}
+			fmt.Fprintf(w, "mysql_statement_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, latency.Count)
+			fmt.Fprintf(w, "mysql_statement_duration_seconds_sum{%s} %s\n", labels, promFloat(latency.Sum.Seconds()))
+			fmt.Fprintf(w, "mysql_statement_duration_seconds_count{%s} %d\n", labels, latency.Count)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP mysql_statement_errors_total Failed statements by operation and MySQL error number (0 for non-server errors).")
+	fmt.Fprintln(w, "# TYPE mysql_statement_errors_total counter")
+	for _, snapshot := range snapshots {
+		for _, e := range snapshot.Errors {
+			labels := promLabels("instance", snapshot.Instance, "operation", e.Operation, "number", strconv.Itoa(int(e.Number)))
+			fmt.Fprintf(w, "mysql_statement_errors_total{%s} %d\n", labels, e.Count)
+		}
+	}
+
+	gauges := []struct {
+		name, help, kind string
+		value            func(stats sql.DBStats) string
+	}{
+		{"mysql_pool_max_open_connections", "Maximum number of open connections.", "gauge",
+			func(s sql.DBStats) string { return strconv.Itoa(s.MaxOpenConnections) }},
+		{"mysql_pool_open_connections", "Number of established connections, in use and idle.", "gauge",
+			func(s sql.DBStats) string { return strconv.Itoa(s.OpenConnections) }},
+		{"mysql_pool_in_use_connections", "Number of connections currently in use.", "gauge",
+			func(s sql.DBStats) string { return strconv.Itoa(s.InUse) }},
+		{"mysql_pool_idle_connections", "Number of idle connections.", "gauge",
+			func(s sql.DBStats) string { return strconv.Itoa(s.Idle) }},
+		{"mysql_pool_wait_count_total", "Total number of connections waited for.", "counter",
+			func(s sql.DBStats) string { return strconv.FormatInt(s.WaitCount, 10) }},
+		{"mysql_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "counter",
+			func(s sql.DBStats) string { return promFloat(s.WaitDuration.Seconds()) }},
+	}
+	for _, gauge := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", gauge.name, gauge.help, gauge.name, gauge.kind)
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(w, "%s{%s} %s\n", gauge.name, promLabels("instance", snapshot.Instance, "pool", "primary"), gauge.value(snapshot.Pool))
+			for n, stats := range snapshot.ReplicaPools {
+				labels := promLabels("instance", snapshot.Instance, "pool", fmt.Sprintf("replica_%d", n))
+				fmt.Fprintf(w, "%s{%s} %s\n", gauge.name, labels, gauge.value(stats))
+			}
+		}
+	}
+}
+
+// 成对的标签名及标签值
+func promLabels(pairs ...string) string {
+	items := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		items = append(items, fmt.Sprintf("%s=\"%s\"", pairs[i], promLabelEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(items, ",")
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
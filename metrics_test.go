@@ -0,0 +1,203 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestMetrics(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	ctx := context.Background()
+	data := map[string]interface{}{"Name": "sam"}
+
+	if _, err := inst.InsertContext(ctx, "user", data); err != nil {
+		t.Fatal(err)
+	}
+	rec.failWith(func(string) error {
+		return &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry 'sam' for key 'user.idx_name'"}
+	})
+	if _, err := inst.InsertContext(ctx, "user", data); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("got error %v, want ErrDuplicateKey", err)
+	}
+	rec.failWith(func(string) error {
+		return errors.New("connection refused")
+	})
+	if _, err := inst.InsertContext(ctx, "user", data); err == nil {
+		t.Fatal("insert should fail")
+	}
+	rec.failWith(nil)
+	if _, err := inst.CountContext(ctx, "user", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := inst.Metrics()
+	if snapshot.Instance != inst.Name() || snapshot.Pool.OpenConnections != 1 {
+		t.Errorf("got instance %q with %d open connections", snapshot.Instance, snapshot.Pool.OpenConnections)
+	}
+
+	var latencies []string
+	for _, latency := range snapshot.Latencies {
+		latencies = append(latencies, latency.Operation+" "+latency.Table)
+		if last := latency.Buckets[len(latency.Buckets)-1]; last.UpperBound != 10*time.Second || last.Count != latency.Count {
+			t.Errorf("%s %s: got last bucket %+v, count %d", latency.Operation, latency.Table, last, latency.Count)
+		}
+	}
+	if want := []string{"insert user", "select user"}; !reflect.DeepEqual(latencies, want) {
+		t.Errorf("got latencies %v, want %v", latencies, want)
+	}
+	if snapshot.Latencies[0].Count != 3 || snapshot.Latencies[1].Count != 1 {
+		t.Errorf("got latency counts %d, %d, want 3, 1", snapshot.Latencies[0].Count, snapshot.Latencies[1].Count)
+	}
+
+	// 非 MySQL 返回的错误记为 0
+	wantErrors := []ErrorCount{{Operation: opInsert, Number: 0, Count: 1}, {Operation: opInsert, Number: errNumDuplicateKey, Count: 1}}
+	if !reflect.DeepEqual(snapshot.Errors, wantErrors) {
+		t.Errorf("got errors %+v, want %+v", snapshot.Errors, wantErrors)
+	}
+}
+
+func TestMetricsBuckets(t *testing.T) {
+	inst, _ := newFakeInstance(t, nil)
+	st := &statement{op: opSelect, table: "user"}
+	// 等于桶上限的耗时计入该桶，超过最大上限的仅计入 +Inf
+	for _, duration := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond, 20 * time.Second} {
+		inst.observe(st, duration, nil)
+	}
+
+	latencies := inst.Metrics().Latencies
+	if len(latencies) != 1 {
+		t.Fatalf("got %d latencies, want 1", len(latencies))
+	}
+	var got []uint64
+	for _, bucket := range latencies[0].Buckets {
+		got = append(got, bucket.Count)
+	}
+	if want := []uint64{2, 2, 2, 3, 3, 3, 3, 3, 3, 3, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got buckets %v, want %v", got, want)
+	}
+	if latencies[0].Count != 4 || latencies[0].Sum != 20036*time.Millisecond {
+		t.Errorf("got count %d, sum %s", latencies[0].Count, latencies[0].Sum)
+	}
+}
+
+func TestDisableMetrics(t *testing.T) {
+	inst, _ := newFakeInstance(t, &Options{DisableStatementLog: true, DisableMetrics: true})
+	if _, err := inst.CountContext(context.Background(), "user", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连接池指标不受影响
+	snapshot := inst.Metrics()
+	if len(snapshot.Latencies) != 0 || len(snapshot.Errors) != 0 || snapshot.Pool.OpenConnections != 1 {
+		t.Errorf("got %+v", snapshot)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	inst, _ := newFakeInstance(t, nil)
+	addFakeReplicas(t, inst, BalanceRoundRobin, 1)
+	setDefault(t, inst)
+
+	inst.observe(&statement{op: opSelect, table: "user"}, 3*time.Millisecond, nil)
+	inst.observe(&statement{op: opSelect, table: "user"}, 40*time.Millisecond, nil)
+	inst.observe(&statement{op: opInsert, table: "user"}, 2*time.Millisecond,
+		translateError(&mysqldriver.MySQLError{Number: errNumDuplicateKey}))
+	inst.observe(&statement{op: opSelect}, time.Millisecond, errors.New("connection refused"))
+
+	// 主库及从库各建立一个空闲连接
+	if _, err := inst.GetDB().Exec("DO 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inst.replicas[0].db.Exec("DO 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got := w.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("got content type %q", got)
+	}
+	if got := w.Body.String(); got != metricsGolden {
+		t.Errorf("got\n%s\nwant\n%s", got, metricsGolden)
+	}
+}
+
+const metricsGolden = `# HELP mysql_statement_duration_seconds Statement latency by operation and table.
+# TYPE mysql_statement_duration_seconds histogram
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.005"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.01"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.025"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.05"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.1"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.25"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="0.5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="1"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="2.5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="10"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="insert",table="user",le="+Inf"} 1
+mysql_statement_duration_seconds_sum{instance="TestMetricsHandler",operation="insert",table="user"} 0.002
+mysql_statement_duration_seconds_count{instance="TestMetricsHandler",operation="insert",table="user"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.005"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.01"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.025"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.05"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.1"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.25"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="0.5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="1"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="2.5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="5"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="10"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="",le="+Inf"} 1
+mysql_statement_duration_seconds_sum{instance="TestMetricsHandler",operation="select",table=""} 0.001
+mysql_statement_duration_seconds_count{instance="TestMetricsHandler",operation="select",table=""} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.005"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.01"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.025"} 1
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.05"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.1"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.25"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="0.5"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="1"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="2.5"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="5"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="10"} 2
+mysql_statement_duration_seconds_bucket{instance="TestMetricsHandler",operation="select",table="user",le="+Inf"} 2
+mysql_statement_duration_seconds_sum{instance="TestMetricsHandler",operation="select",table="user"} 0.043
+mysql_statement_duration_seconds_count{instance="TestMetricsHandler",operation="select",table="user"} 2
+# HELP mysql_statement_errors_total Failed statements by operation and MySQL error number (0 for non-server errors).
+# TYPE mysql_statement_errors_total counter
+mysql_statement_errors_total{instance="TestMetricsHandler",operation="insert",number="1062"} 1
+mysql_statement_errors_total{instance="TestMetricsHandler",operation="select",number="0"} 1
+# HELP mysql_pool_max_open_connections Maximum number of open connections.
+# TYPE mysql_pool_max_open_connections gauge
+mysql_pool_max_open_connections{instance="TestMetricsHandler",pool="primary"} 0
+mysql_pool_max_open_connections{instance="TestMetricsHandler",pool="replica_0"} 0
+# HELP mysql_pool_open_connections Number of established connections, in use and idle.
+# TYPE mysql_pool_open_connections gauge
+mysql_pool_open_connections{instance="TestMetricsHandler",pool="primary"} 1
+mysql_pool_open_connections{instance="TestMetricsHandler",pool="replica_0"} 1
+# HELP mysql_pool_in_use_connections Number of connections currently in use.
+# TYPE mysql_pool_in_use_connections gauge
+mysql_pool_in_use_connections{instance="TestMetricsHandler",pool="primary"} 0
+mysql_pool_in_use_connections{instance="TestMetricsHandler",pool="replica_0"} 0
+# HELP mysql_pool_idle_connections Number of idle connections.
+# TYPE mysql_pool_idle_connections gauge
+mysql_pool_idle_connections{instance="TestMetricsHandler",pool="primary"} 1
+mysql_pool_idle_connections{instance="TestMetricsHandler",pool="replica_0"} 1
+# HELP mysql_pool_wait_count_total Total number of connections waited for.
+# TYPE mysql_pool_wait_count_total counter
+mysql_pool_wait_count_total{instance="TestMetricsHandler",pool="primary"} 0
+mysql_pool_wait_count_total{instance="TestMetricsHandler",pool="replica_0"} 0
+# HELP mysql_pool_wait_duration_seconds_total Total time blocked waiting for a new connection.
+# TYPE mysql_pool_wait_duration_seconds_total counter
+mysql_pool_wait_duration_seconds_total{instance="TestMetricsHandler",pool="primary"} 0
+mysql_pool_wait_duration_seconds_total{instance="TestMetricsHandler",pool="replica_0"} 0
+`
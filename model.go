@@ -1,13 +1,14 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
-
-	"github.com/hzxgo/log"
 )
 
 // ---------------------------------------------------------------------------------------------------------------------
@@ -25,35 +26,33 @@ func Select(fields string) *Query {
 }
 
 // 基于SQL查询
-func SelectBySql(cmd string, value ...interface{}) (*sql.Rows, error) {
-	if len(value) == 0 {
-		log.Infof("[MySQL]: %s ", cmd)
-	} else {
-		log.Infof("[MySQL]: %s | %+v", cmd, value)
-	}
+func (s *session) SelectBySql(cmd string, value ...interface{}) (*sql.Rows, error) {
+	return s.SelectBySqlContext(s.baseContext(), cmd, value...)
+}
 
-	return DB.Query(cmd, value...)
+// 基于SQL查询，配置了从库时路由至从库
+func (s *session) SelectBySqlContext(ctx context.Context, cmd string, value ...interface{}) (*sql.Rows, error) {
+	return s.query(ctx, &statement{op: opSelect, sql: cmd, args: value})
 }
 
 // 查询记录
-func SelectWhere(query *Query, exp interface{}) (*sql.Rows, error) {
-	if query == nil {
-		return nil, fmt.Errorf("params error")
-	}
-
-	var err error
-	if query.Where, err = getWhereByInterface(exp); err != nil {
-		return nil, err
-	}
-
-	cmd := query.Combination()
-	log.Infof("[MySQL]: %s", cmd)
+func (s *session) SelectWhere(query *Query, exp interface{}) (*sql.Rows, error) {
+	return s.SelectWhereContext(s.baseContext(), query, exp)
+}
 
-	return DB.Query(cmd)
+// 查询记录，配置了从库时路由至从库
+func (s *session) SelectWhereContext(ctx context.Context, query *Query, exp interface{}) (*sql.Rows, error) {
+	rows, _, err := s.selectWhere(ctx, query, exp)
+	return rows, err
 }
 
 // 插入数据：支持 对象指针类型 和 Map 类型
-func Insert(tableName string, data interface{}) (int64, error) {
+func (s *session) Insert(tableName string, data interface{}) (int64, error) {
+	return s.InsertContext(s.baseContext(), tableName, data)
+}
+
+// 插入数据：支持 对象指针类型 和 Map 类型，ctx 取消或超时时中止执行
+func (s *session) InsertContext(ctx context.Context, tableName string, data interface{}) (int64, error) {
 	t := reflect.TypeOf(data)
 
 	switch t.Kind() {
@@ -61,12 +60,12 @@ func Insert(tableName string, data interface{}) (int64, error) {
 		if mapping, err := struct2Map(data); err != nil {
 			return 0, err
 		} else {
-			return insert(mapping, tableName)
+			return s.insert(ctx, mapping, tableName, sensitiveColumns(data))
 		}
 	case reflect.Map:
 		switch data.(type) {
 		case map[string]interface{}:
-			return insert(data.(map[string]interface{}), tableName)
+			return s.insert(ctx, data.(map[string]interface{}), tableName, nil)
 		default:
 		}
 	default:
@@ -75,14 +74,17 @@ func Insert(tableName string, data interface{}) (int64, error) {
 	return 0, errTypeInvalid
 }
 
-// 插入数据：支持 对象指针类型 和 Map 类型
-func InsertBySql(cmd string, value ...interface{}) (int64, error) {
+// 基于SQL插入数据
+func (s *session) InsertBySql(cmd string, value ...interface{}) (int64, error) {
+	return s.InsertBySqlContext(s.baseContext(), cmd, value...)
+}
+
+// 基于SQL插入数据，ctx 取消或超时时中止执行
+func (s *session) InsertBySqlContext(ctx context.Context, cmd string, value ...interface{}) (int64, error) {
 	var err error
 	var result sql.Result
 
-	log.Infof("[MySQL]: %s", cmd)
-
-	if result, err = DB.Exec(cmd, value...); err != nil {
+	if result, err = s.execute(ctx, &statement{op: opInsert, sql: cmd, args: value}); err != nil {
 		return 0, err
 	}
 
@@ -91,7 +93,12 @@ func InsertBySql(cmd string, value ...interface{}) (int64, error) {
 
 // 插入多条记录：支持 对象指针类型 和 Map 类型
 // 返回值：最后插入的id，插入的数量，错误信息
-func MInsert(tableName string, data ...interface{}) (int64, int64, error) {
+func (s *session) MInsert(tableName string, data ...interface{}) (int64, int64, error) {
+	return s.MInsertContext(s.baseContext(), tableName, data...)
+}
+
+// 插入多条记录：支持 对象指针类型 和 Map 类型，ctx 取消或超时时中止执行
+func (s *session) MInsertContext(ctx context.Context, tableName string, data ...interface{}) (int64, int64, error) {
 	var dataLen int
 	if dataLen = len(data); dataLen == 0 {
 		return 0, 0, errParamsBad
@@ -113,7 +120,7 @@ func MInsert(tableName string, data ...interface{}) (int64, int64, error) {
 				values = append(values, ptrValues)
 			}
 		}
-		return BatchInsert(tableName, columns, values)
+		return s.batchInsert(ctx, tableName, columns, values, sensitiveColumns(data[0]))
 	case reflect.Map:
 		switch data[0].(type) {
 		case map[string]interface{}:
@@ -133,7 +140,7 @@ func MInsert(tableName string, data ...interface{}) (int64, int64, error) {
 				}
 				values = append(values, subMapValues)
 			}
-			return BatchInsert(tableName, columns, values)
+			return s.batchInsert(ctx, tableName, columns, values, nil)
 		}
 	}
 
@@ -141,7 +148,12 @@ func MInsert(tableName string, data ...interface{}) (int64, int64, error) {
 }
 
 // 更新：基于exp表达式更新data数据
-func Update(tableName string, data interface{}, exp interface{}) (int64, error) {
+func (s *session) Update(tableName string, data interface{}, exp interface{}) (int64, error) {
+	return s.UpdateContext(s.baseContext(), tableName, data, exp)
+}
+
+// 更新：基于exp表达式更新data数据，ctx 取消或超时时中止执行
+func (s *session) UpdateContext(ctx context.Context, tableName string, data interface{}, exp interface{}) (int64, error) {
 	t := reflect.TypeOf(data)
 
 	switch t.Kind() {
@@ -149,12 +161,12 @@ func Update(tableName string, data interface{}, exp interface{}) (int64, error)
 		if mapping, err := struct2Map(data); err != nil {
 			return 0, err
 		} else {
-			return update(mapping, exp, tableName)
+			return s.update(ctx, mapping, exp, tableName, sensitiveColumns(data))
 		}
 	case reflect.Map:
 		switch data.(type) {
 		case map[string]interface{}:
-			return update(data.(map[string]interface{}), exp, tableName)
+			return s.update(ctx, data.(map[string]interface{}), exp, tableName, nil)
 		default:
 		}
 	default:
@@ -164,13 +176,16 @@ func Update(tableName string, data interface{}, exp interface{}) (int64, error)
 }
 
 // 基于SQL更新
-func UpdateBySql(cmd string, value ...interface{}) (int64, error) {
+func (s *session) UpdateBySql(cmd string, value ...interface{}) (int64, error) {
+	return s.UpdateBySqlContext(s.baseContext(), cmd, value...)
+}
+
+// 基于SQL更新，ctx 取消或超时时中止执行
+func (s *session) UpdateBySqlContext(ctx context.Context, cmd string, value ...interface{}) (int64, error) {
 	var err error
 	var result sql.Result
 
-	log.Infof("[MySQL]: %s", cmd)
-
-	if result, err = DB.Exec(cmd, value...); err != nil {
+	if result, err = s.execute(ctx, &statement{op: opUpdate, sql: cmd, args: value}); err != nil {
 		return 0, err
 	}
 
@@ -178,18 +193,25 @@ func UpdateBySql(cmd string, value ...interface{}) (int64, error) {
 }
 
 // 删除：基于exp表达式删除数据
-func Delete(tableName string, exp interface{}) (int64, error) {
+func (s *session) Delete(tableName string, exp interface{}) (int64, error) {
+	return s.DeleteContext(s.baseContext(), tableName, exp)
+}
+
+// 删除：基于exp表达式删除数据，ctx 取消或超时时中止执行
+func (s *session) DeleteContext(ctx context.Context, tableName string, exp interface{}) (int64, error) {
 	var result sql.Result
 
-	retWhere, err := getWhereByInterface(exp)
+	retWhere, args, columns, err := getWhereByInterface(exp)
 	if err != nil {
 		return 0, err
 	}
+	if retWhere == "" && !isAllRows(exp) {
+		return 0, ErrFullTableWrite
+	}
 
-	cmd := fmt.Sprintf("DELETE FROM `%v` %v", tableName, retWhere)
-	log.Infof("[MySQL]: %s", cmd)
-
-	if result, err = DB.Exec(cmd); err != nil {
+	cmd := fmt.Sprintf("DELETE FROM %s %v", QuoteIdentifier(tableName), retWhere)
+	st := &statement{op: opDelete, table: tableName, sql: cmd, args: args, columns: columns}
+	if result, err = s.execute(ctx, st); err != nil {
 		return 0, err
 	}
 
@@ -197,10 +219,29 @@ func Delete(tableName string, exp interface{}) (int64, error) {
 }
 
 // 批量插入数据
-func BatchInsert(tableName string, columns []string, params []interface{}) (int64, int64, error) {
+func (s *session) BatchInsert(tableName string, columns []string, params []interface{}) (int64, int64, error) {
+	return s.BatchInsertContext(s.baseContext(), tableName, columns, params)
+}
+
+// 批量插入数据，ctx 取消或超时时中止执行
+func (s *session) BatchInsertContext(ctx context.Context, tableName string, columns []string, params []interface{}) (int64, int64, error) {
+	return s.batchInsert(ctx, tableName, columns, params, nil)
+}
+
+// 批量插入数据，sensitive 为通过 db 标签标记为敏感的字段
+func (s *session) batchInsert(ctx context.Context, tableName string, columns []string, params []interface{}, sensitive map[string]bool) (int64, int64, error) {
 	var err error
 	var lastInsertId, affected int64
 
+	// 每个分批的语句 span 均为该 span 的子 span
+	if tracer := s.tracer(); tracer != nil {
+		var span Span
+		ctx, span = tracer.Start(ctx, spanName(opBatchInsert, tableName),
+			Field{AttrDBSystem, "mysql"}, Field{AttrDBOperation, opBatchInsert},
+			Field{AttrDBTable, tableName}, Field{AttrBatchSize, len(params)})
+		defer func() { endSpan(span, err) }()
+	}
+
 	paramsLen := len(params)
 	count, fraction := math.Modf(float64(paramsLen) / maxBatchLimit)
 	if fraction > 0.000001 {
@@ -215,7 +256,7 @@ func BatchInsert(tableName string, columns []string, params []interface{}) (int6
 			endIndex = (i + 1) * maxBatchLimit
 		}
 
-		lastInsertId, affected, err = batchInsertByLimit(columns, params[i*maxBatchLimit:endIndex], tableName)
+		lastInsertId, affected, err = s.batchInsertByLimit(ctx, columns, params[i*maxBatchLimit:endIndex], tableName, sensitive)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -224,12 +265,13 @@ func BatchInsert(tableName string, columns []string, params []interface{}) (int6
 	return lastInsertId, affected, err
 }
 
-// 加载一个值
+// 加载一个值，没有数据时返回的错误可通过 errors.Is(err, sql.ErrNoRows) 判断
 func LoadValue(rows *sql.Rows, value interface{}) error {
+	st := rowsStatement(rows)
 	if count, err := Load(rows, value); err != nil {
 		return err
 	} else if count == 0 {
-		return ErrNoRows()
+		return wrapError(st, ErrNoRows())
 	} else {
 		return nil
 	}
@@ -240,12 +282,13 @@ func LoadValues(rows *sql.Rows, value interface{}) (int, error) {
 	return Load(rows, value)
 }
 
-// 加载结构体
+// 加载结构体，没有数据时返回的错误可通过 errors.Is(err, sql.ErrNoRows) 判断
 func LoadStruct(rows *sql.Rows, value interface{}) error {
+	st := rowsStatement(rows)
 	if count, err := Load(rows, value); err != nil {
 		return err
 	} else if count == 0 {
-		return ErrNoRows()
+		return wrapError(st, ErrNoRows())
 	} else {
 		return nil
 	}
@@ -256,8 +299,87 @@ func LoadStructs(rows *sql.Rows, value interface{}) (int, error) {
 	return Load(rows, value)
 }
 
-// 万能加载
+// 万能加载，读取失败时返回 *Error，包含出错的列、结构体字段及查询语句
 func Load(rows *sql.Rows, value interface{}) (int, error) {
+	return load(rows, rowsStatement(rows), value)
+}
+
+// 基于条件表达式判断数据是否存在
+func (s *session) IsExist(tableName string, exp interface{}, field string, value string) (bool, error) {
+	return s.IsExistContext(s.baseContext(), tableName, exp, field, value)
+}
+
+// 基于条件表达式判断数据是否存在，配置了从库时路由至从库
+func (s *session) IsExistContext(ctx context.Context, tableName string, exp interface{}, field string, value string) (bool, error) {
+	var key string
+	query := Select(field).Form(tableName)
+	if rows, st, err := s.selectWhere(ctx, query, exp); err != nil {
+		return false, err
+	} else {
+		if _, err = load(rows, st, &key); err != nil {
+			return false, err
+		}
+		if key == "" || key == value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 统计
+func (s *session) Count(tableName string, exp interface{}) (int, error) {
+	return s.CountContext(s.baseContext(), tableName, exp)
+}
+
+// 统计，配置了从库时路由至从库
+func (s *session) CountContext(ctx context.Context, tableName string, exp interface{}) (int, error) {
+	var total int
+	query := Select("COUNT(0)").Form(tableName)
+	if rows, st, err := s.selectWhere(ctx, query, exp); err != nil {
+		return 0, err
+	} else {
+		if _, err = load(rows, st, &total); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// 没有数据，LoadValue/LoadStruct 返回的错误需通过 errors.Is(err, ErrNoRows()) 判断
+func ErrNoRows() error {
+	return sql.ErrNoRows
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 查询记录，同时返回执行的语句，供读取结果出错时使用
+func (s *session) selectWhere(ctx context.Context, query *Query, exp interface{}) (*sql.Rows, *statement, error) {
+	if query == nil {
+		return nil, nil, fmt.Errorf("params error")
+	}
+	if query.err != nil {
+		return nil, nil, query.err
+	}
+
+	var err error
+	var args []interface{}
+	var columns []string
+	if query.Where, args, columns, err = getWhereByInterface(exp); err != nil {
+		return nil, nil, err
+	}
+	// 提示仅作用于本次执行，不写回 query，避免复用时沿用
+	timeout := query.timeout
+	if timeout == 0 {
+		timeout = s.queryTimeout(ctx)
+	}
+
+	st := &statement{op: opSelect, table: query.table, sql: query.combine(timeout), args: args, columns: columns}
+	rows, err := s.query(ctx, st)
+	return rows, st, err
+}
+
+// 读取查询结果，st 为查询对应的语句，未知时为 nil
+func load(rows *sql.Rows, st *statement, value interface{}) (count int, err error) {
 	if rows == nil {
 		return 0, errParamsBad
 	}
@@ -270,10 +392,9 @@ func Load(rows *sql.Rows, value interface{}) (int, error) {
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return 0, err
+		return 0, wrapError(st, err)
 	}
 
-	count := 0
 	v = v.Elem()
 	isSlice := v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8
 	for rows.Next() {
@@ -284,10 +405,10 @@ func Load(rows *sql.Rows, value interface{}) (int, error) {
 			elem = v
 		}
 		if ptr, err := findPtr(columns, elem); err != nil {
-			return 0, err
+			return 0, wrapError(st, err)
 		} else {
 			if err = rows.Scan(ptr...); err != nil {
-				return 0, err
+				return 0, wrapScanError(st, columns, elem, err)
 			}
 		}
 		count++
@@ -297,49 +418,15 @@ func Load(rows *sql.Rows, value interface{}) (int, error) {
 			break
 		}
 	}
-
-	return count, nil
-}
-
-// 基于条件表达式判断数据是否存在
-func IsExist(tableName string, exp interface{}, field string, value string) (bool, error) {
-	var key string
-	query := Select(field).Form(tableName)
-	if rows, err := SelectWhere(query, exp); err != nil {
-		return false, err
-	} else {
-		if _, err = Load(rows, &key); err != nil {
-			return false, err
-		}
-		if key == "" || key == value {
-			return false, nil
-		}
+	if err = rows.Err(); err != nil {
+		return 0, wrapError(st, err)
 	}
-	return true, nil
-}
 
-// 统计
-func Count(tableName string, exp interface{}) (int, error) {
-	var total int
-	query := Select("COUNT(0)").Form(tableName)
-	if rows, err := SelectWhere(query, exp); err != nil {
-		return 0, err
-	} else {
-		if _, err = Load(rows, &total); err != nil {
-			return 0, err
-		}
-	}
-	return total, nil
-}
-
-func ErrNoRows() error {
-	return sql.ErrNoRows
+	return count, nil
 }
 
-// ---------------------------------------------------------------------------------------------------------------------
-
-// 插入params数据
-func insert(params map[string]interface{}, tableName string) (int64, error) {
+// 插入params数据，值均以 ? 占位符的方式交由驱动处理，Null* 类型的无效值会写入 NULL
+func (s *session) insert(ctx context.Context, params map[string]interface{}, tableName string, sensitive map[string]bool) (int64, error) {
 	if len(params) == 0 {
 		return 0, errParamsBad
 	}
@@ -349,36 +436,25 @@ func insert(params map[string]interface{}, tableName string) (int64, error) {
 
 	length := len(params)
 	columns := make([]string, 0, length)
-	values := make([]string, 0, length)
-	for key, value := range params {
-		switch value.(type) {
-		case NullString:
-			value = value.(NullString).String
-		case sql.NullString:
-			value = value.(sql.NullString).String
-		case NullBool:
-			value = value.(NullBool).Bool
-		case sql.NullBool:
-			value = value.(sql.NullBool).Bool
-		case NullInt64:
-			value = value.(NullInt64).Int64
-		case sql.NullInt64:
-			value = value.(sql.NullInt64).Int64
-		case NullFloat64:
-			value = value.(NullFloat64).Float64
-		case sql.NullFloat64:
-			value = value.(sql.NullFloat64).Float64
-		}
+	for key := range params {
 		columns = append(columns, key)
-		values = append(values, fmt.Sprintf("%v", value))
 	}
+	sort.Strings(columns)
 
-	fields := fmt.Sprintf("`%s`", strings.Join(columns, "`,`"))
-	fieldValues := fmt.Sprintf("'%s'", strings.Join(values, "','"))
-	cmd := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES(%s)", tableName, fields, fieldValues)
-	log.Infof("[MySQL]: %s", cmd)
+	placeholders := make([]string, 0, length)
+	args := make([]interface{}, 0, length)
+	argColumns := make([]string, 0, length)
+	for _, column := range columns {
+		placeholder, valueArgs := getPlaceholder(params[column])
+		placeholders = append(placeholders, placeholder)
+		args = append(args, valueArgs...)
+		argColumns = appendColumn(argColumns, column, len(valueArgs))
+	}
 
-	if result, err = DB.Exec(cmd); err != nil {
+	fields := strings.Join(quoteIdentifiers(columns), ",")
+	cmd := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", QuoteIdentifier(tableName), fields, strings.Join(placeholders, ","))
+	st := &statement{op: opInsert, table: tableName, sql: cmd, args: args, columns: argColumns, sensitive: sensitive}
+	if result, err = s.execute(ctx, st); err != nil {
 		return 0, err
 	}
 
@@ -386,126 +462,237 @@ func insert(params map[string]interface{}, tableName string) (int64, error) {
 }
 
 // 更新：基于exp表达式更新params数据
-func update(params map[string]interface{}, exp interface{}, tableName string) (int64, error) {
+func (s *session) update(ctx context.Context, params map[string]interface{}, exp interface{}, tableName string, sensitive map[string]bool) (int64, error) {
 	var result sql.Result
 
-	retWhere, err := getWhereByInterface(exp)
+	retWhere, args, whereColumns, err := getWhereByInterface(exp)
 	if err != nil {
 		return 0, err
 	}
+	if retWhere == "" && !isAllRows(exp) {
+		return 0, ErrFullTableWrite
+	}
 
 	length := len(params)
+	columns := make([]string, 0, length)
+	for key := range params {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+
 	setValues := make([]string, 0, length)
-	for key, value := range params {
-		set := fmt.Sprintf("`%v`='%v'", key, value)
-		setValues = append(setValues, set)
+	setArgs := make([]interface{}, 0, length+len(args))
+	setColumns := make([]string, 0, length+len(args))
+	for _, column := range columns {
+		placeholder, valueArgs := getPlaceholder(params[column])
+		setValues = append(setValues, fmt.Sprintf("%s=%s", QuoteIdentifier(column), placeholder))
+		setArgs = append(setArgs, valueArgs...)
+		setColumns = appendColumn(setColumns, column, len(valueArgs))
 	}
 
 	retSet := strings.Join(setValues, ", ")
-	cmd := fmt.Sprintf("UPDATE `%s` SET %s %s", tableName, retSet, retWhere)
-	log.Infof("[MySQL]: %s", cmd)
-
-	if result, err = DB.Exec(cmd); err != nil {
+	cmd := fmt.Sprintf("UPDATE %s SET %s %s", QuoteIdentifier(tableName), retSet, retWhere)
+	st := &statement{
+		op:        opUpdate,
+		table:     tableName,
+		sql:       cmd,
+		args:      append(setArgs, args...),
+		columns:   append(setColumns, whereColumns...),
+		sensitive: sensitive,
+	}
+	if result, err = s.execute(ctx, st); err != nil {
 		return 0, err
 	}
 
 	return result.RowsAffected()
 }
 
-// 基于表达式获取并构建where语句
-func getWhereByInterface(exp interface{}) (string, error) {
+// 基于表达式获取并构建where语句，返回带 ? 占位符的where语句、对应的参数及各参数所属的字段
+func getWhereByInterface(exp interface{}) (string, []interface{}, []string, error) {
 	var result string
+	var args []interface{}
+	var columns []string
 
 	if exp == nil {
-		return "", nil
+		return "", nil, nil, nil
 	}
 
 	switch exp.(type) {
+	case allRowsExp:
+
 	case map[string]interface{}:
 		if len(exp.(map[string]interface{})) > 0 {
-			result = fmt.Sprintf(" WHERE %s", getWhereItem("AND", exp.(map[string]interface{})))
+			var item string
+			var err error
+			if item, args, columns, err = getWhereItem("AND", exp.(map[string]interface{})); err != nil {
+				return "", nil, nil, err
+			}
+			result = fmt.Sprintf(" WHERE %s", item)
 		}
 
 	case map[string]map[string]interface{}:
 		length := len(exp.(map[string]map[string]interface{}))
 		if length > 0 {
+			joins := make([]string, 0, length)
+			for key := range exp.(map[string]map[string]interface{}) {
+				joins = append(joins, key)
+			}
+			sort.Strings(joins)
+
 			wheres := make([]string, 0, length)
-			for key, value := range exp.(map[string]map[string]interface{}) {
+			for _, key := range joins {
 				keyToUpper := strings.ToUpper(key)
 				if keyToUpper == "AND" || keyToUpper == "OR" {
-					wheres = append(wheres, getWhereItem(keyToUpper, value))
+					item, itemArgs, itemColumns, err := getWhereItem(keyToUpper, exp.(map[string]map[string]interface{})[key])
+					if err != nil {
+						return "", nil, nil, err
+					}
+					if item != "" {
+						wheres = append(wheres, item)
+						args = append(args, itemArgs...)
+						columns = append(columns, itemColumns...)
+					}
 				} else {
-					return "", errParamsBad
+					return "", nil, nil, errParamsBad
 				}
 			}
-			result = fmt.Sprintf(" WHERE %s", strings.Join(wheres, " AND "))
+			if len(wheres) > 0 {
+				result = fmt.Sprintf(" WHERE %s", strings.Join(wheres, " AND "))
+			}
 		}
 
 	default:
-		return "", errParamsBad
+		return "", nil, nil, errParamsBad
 	}
 
-	return result, nil
+	return result, args, columns, nil
+}
+
+// 是否为 AllRows() 标记
+func isAllRows(exp interface{}) bool {
+	_, ok := exp.(allRowsExp)
+	return ok
 }
 
-// 获取并构建where中的每个子项
-func getWhereItem(join string, exp map[string]interface{}) string {
+// 获取并构建where中的每个子项，key中的每个 ? 都对应一个value参数
+// 若value为切片(如 "ID IN (?)")，则将 ? 展开为与切片等长的占位符
+func getWhereItem(join string, exp map[string]interface{}) (string, []interface{}, []string, error) {
 	var result string
+	var args []interface{}
+	var columns []string
 
 	if length := len(exp); length > 0 {
+		keys := make([]string, 0, length)
+		for key := range exp {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		where := make([]string, 0, length)
-		for key, value := range exp {
-			where = append(where, strings.Replace(key, "?", fmt.Sprintf("'%v'", value), -1))
+		for _, key := range keys {
+			value := exp[key]
+			count := strings.Count(key, "?")
+			if count == 0 {
+				where = append(where, key)
+				continue
+			}
+			argsLen := len(args)
+
+			if expr, ok := value.(SqlExpr); ok {
+				where = append(where, strings.Replace(key, "?", expr.sql, -1))
+				for i := 0; i < count; i++ {
+					args = append(args, expr.args...)
+				}
+			} else if elems, ok := expandSlice(value); ok {
+				if len(elems) == 0 {
+					return "", nil, nil, ErrEmptySlice
+				}
+				placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(elems)), ", ")
+				where = append(where, strings.Replace(key, "?", placeholders, -1))
+				for i := 0; i < count; i++ {
+					args = append(args, elems...)
+				}
+			} else {
+				where = append(where, key)
+				for i := 0; i < count; i++ {
+					args = append(args, value)
+				}
+			}
+			columns = appendColumn(columns, whereColumn(key), len(args)-argsLen)
 		}
 		result = fmt.Sprintf("(%s)", strings.Join(where, fmt.Sprintf(" %s ", join)))
 	}
 
-	return result
+	return result, args, columns, nil
+}
+
+// 获取值对应的占位符及参数：SqlExpr 原样拼接其SQL，其他值使用 ?
+func getPlaceholder(value interface{}) (string, []interface{}) {
+	if expr, ok := value.(SqlExpr); ok {
+		return expr.sql, expr.args
+	}
+	return "?", []interface{}{value}
 }
 
-func batchInsertByLimit(columns []string, params []interface{}, tableName string) (int64, int64, error) {
+// 将切片类型的值展开，元素为 byte 的切片(如 []byte、json.RawMessage、sql.RawBytes)
+// 及实现了 driver.Valuer 的切片(如自定义的 JSON、数组类型)作为单个值处理
+func expandSlice(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elems[i] = v.Index(i).Interface()
+	}
+	return elems, true
+}
+
+func (s *session) batchInsertByLimit(ctx context.Context, columns []string, params []interface{}, tableName string, sensitive map[string]bool) (int64, int64, error) {
 	paramsLen := len(params)
 	if paramsLen > maxBatchLimit {
 		return 0, 0, fmt.Errorf("batch insert too large, length: %v", paramsLen)
 	}
 
 	// 防止字段是关键字，所以加上转义符号，如：`status`
-	for key, value := range columns {
-		columns[key] = fmt.Sprintf("`%s`", value)
-	}
+	fields := quoteIdentifiers(columns)
 
 	data := make([]string, paramsLen)
+	args := make([]interface{}, 0, paramsLen*len(columns))
+	argColumns := make([]string, 0, paramsLen*len(columns))
 	for i, v := range params {
 		val := reflect.ValueOf(v)
 		if val.Kind() != reflect.Slice {
 			return 0, 0, fmt.Errorf("params error, insert data must be slice")
 		}
+		if val.Len() != len(columns) {
+			return 0, 0, fmt.Errorf("params error, insert data length %d not match columns length %d", val.Len(), len(columns))
+		}
 
-		var subVal string
-		subData := make([]string, 0, val.Len())
+		placeholders := make([]string, 0, val.Len())
 		for j := 0; j < val.Len(); j++ {
-			switch t := val.Index(j).Interface().(type) {
-			case string:
-				subVal = fmt.Sprintf("'%s'", t)
-			case NullString:
-				subVal = fmt.Sprintf("'%s'", t.String)
-			case sql.NullString:
-				subVal = fmt.Sprintf("'%s'", t.String)
-			default:
-				subVal = fmt.Sprintf("%v", t)
-			}
-			subData = append(subData, subVal)
+			placeholder, valueArgs := getPlaceholder(val.Index(j).Interface())
+			placeholders = append(placeholders, placeholder)
+			args = append(args, valueArgs...)
+			argColumns = appendColumn(argColumns, columns[j], len(valueArgs))
 		}
-		data[i] = fmt.Sprintf("(%s)", strings.Join(subData, ","))
+		data[i] = fmt.Sprintf("(%s)", strings.Join(placeholders, ","))
 	}
 
 	var err error
 	var result sql.Result
-	cmd := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s",
-		tableName, strings.Join(columns, ","), strings.Join(data, ","))
-	log.Infof("[MySQL]: %s", cmd)
-
-	if result, err = DB.Exec(cmd); err != nil {
+	cmd := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		QuoteIdentifier(tableName), strings.Join(fields, ","), strings.Join(data, ","))
+	st := &statement{op: opBatchInsert, table: tableName, sql: cmd, args: args, columns: argColumns, sensitive: sensitive}
+	if result, err = s.execute(ctx, st); err != nil {
 		return 0, 0, err
 	}
 
@@ -0,0 +1,346 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以逗号分隔存储的列表
+type tagList []string
+
+func (l tagList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+func TestGetWhereByInterface(t *testing.T) {
+	cases := []struct {
+		name    string
+		exp     interface{}
+		where   string
+		args    []interface{}
+		columns []string
+		err     error
+	}{
+		{name: "nil", exp: nil},
+		{name: "all rows", exp: AllRows()},
+		{name: "empty map", exp: map[string]interface{}{}},
+		{
+			name:    "placeholder",
+			exp:     map[string]interface{}{"ID = ?": 1},
+			where:   " WHERE (ID = ?)",
+			args:    []interface{}{1},
+			columns: []string{"ID"},
+		},
+		{
+			name:    "quote is passed as arg",
+			exp:     map[string]interface{}{"Name = ?": "o'neil", "Age > ?": 18},
+			where:   " WHERE (Age > ? AND Name = ?)",
+			args:    []interface{}{18, "o'neil"},
+			columns: []string{"Age", "Name"},
+		},
+		{
+			name:    "key without placeholder",
+			exp:     map[string]interface{}{"DeletedAt IS NULL": nil, "`user`.`ID` = ?": 1},
+			where:   " WHERE (DeletedAt IS NULL AND `user`.`ID` = ?)",
+			args:    []interface{}{1},
+			columns: []string{"ID"},
+		},
+		{
+			name:    "repeated placeholder",
+			exp:     map[string]interface{}{"(Mobile = ? OR Email = ?)": "sam"},
+			where:   " WHERE ((Mobile = ? OR Email = ?))",
+			args:    []interface{}{"sam", "sam"},
+			columns: []string{"Mobile", "Mobile"},
+		},
+		{
+			name:    "slice is expanded",
+			exp:     map[string]interface{}{"ID IN (?)": []int{1, 2, 3}},
+			where:   " WHERE (ID IN (?, ?, ?))",
+			args:    []interface{}{1, 2, 3},
+			columns: []string{"ID", "ID", "ID"},
+		},
+		{
+			name:    "bytes are not expanded",
+			exp:     map[string]interface{}{"Data = ?": []byte("ab")},
+			where:   " WHERE (Data = ?)",
+			args:    []interface{}{[]byte("ab")},
+			columns: []string{"Data"},
+		},
+		{
+			name:    "named bytes are not expanded",
+			exp:     map[string]interface{}{"Extra = ?": json.RawMessage(`{}`)},
+			where:   " WHERE (Extra = ?)",
+			args:    []interface{}{json.RawMessage(`{}`)},
+			columns: []string{"Extra"},
+		},
+		{
+			name:    "valuer is not expanded",
+			exp:     map[string]interface{}{"Tags = ?": tagList{"a", "b"}},
+			where:   " WHERE (Tags = ?)",
+			args:    []interface{}{tagList{"a", "b"}},
+			columns: []string{"Tags"},
+		},
+		{
+			name: "empty slice",
+			exp:  map[string]interface{}{"ID IN (?)": []int{}},
+			err:  ErrEmptySlice,
+		},
+		{
+			name:    "expr is spliced",
+			exp:     map[string]interface{}{"Score > ?": Expr("`Limit` + ?", 10)},
+			where:   " WHERE (Score > `Limit` + ?)",
+			args:    []interface{}{10},
+			columns: []string{"Score"},
+		},
+		{
+			name: "and or",
+			exp: map[string]map[string]interface{}{
+				"AND": {"ID > ?": 5},
+				"OR":  {"IsAdmin = ?": 1, "LoginTimes = ?": 0},
+			},
+			where:   " WHERE (ID > ?) AND (IsAdmin = ? OR LoginTimes = ?)",
+			args:    []interface{}{5, 1, 0},
+			columns: []string{"ID", "IsAdmin", "LoginTimes"},
+		},
+		{
+			name: "bad join",
+			exp:  map[string]map[string]interface{}{"XOR": {"ID > ?": 5}},
+			err:  errParamsBad,
+		},
+		{
+			name: "bad type",
+			exp:  "ID = 1",
+			err:  errParamsBad,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			where, args, columns, err := getWhereByInterface(c.exp)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("err = %v, want %v", err, c.err)
+			}
+			if where != c.where {
+				t.Errorf("where = %q, want %q", where, c.where)
+			}
+			if !reflect.DeepEqual(args, c.args) {
+				t.Errorf("args = %#v, want %#v", args, c.args)
+			}
+			if !reflect.DeepEqual(columns, c.columns) {
+				t.Errorf("columns = %q, want %q", columns, c.columns)
+			}
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	type user struct {
+		ID      int64  `db:"-"`
+		Name    string `db:"Name"`
+		Nick    NullString
+		Score   NullInt64 `db:"Score"`
+		Created time.Time `db:"Created"`
+	}
+
+	cases := []struct {
+		name string
+		data interface{}
+		sql  string
+		args []interface{}
+		err  error
+	}{
+		{
+			name: "map",
+			data: map[string]interface{}{"Name": "o'neil\\", "Age": 18, "Deleted": nil, "Nick": NullString{}},
+			sql:  "INSERT INTO `user` (`Age`,`Deleted`,`Name`,`Nick`) VALUES(?,?,?,?)",
+			args: []interface{}{int64(18), nil, "o'neil\\", nil},
+		},
+		{
+			name: "expr",
+			data: map[string]interface{}{"Name": "sam", "Created": Expr("NOW()"), "Code": Expr("UPPER(?)", "ab")},
+			sql:  "INSERT INTO `user` (`Code`,`Created`,`Name`) VALUES(UPPER(?),NOW(),?)",
+			args: []interface{}{"ab", "sam"},
+		},
+		{
+			name: "struct",
+			data: &user{ID: 1, Name: "sam", Score: NullInt64{NullInt64: sql.NullInt64{Int64: 9, Valid: true}}, Created: created},
+			sql:  "INSERT INTO `user` (`Created`,`Name`,`Nick`,`Score`) VALUES(?,?,?,?)",
+			args: []interface{}{created, "sam", nil, int64(9)},
+		},
+		{name: "empty map", data: map[string]interface{}{}, err: errParamsBad},
+		{name: "bad type", data: []string{"sam"}, err: errTypeInvalid},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			if _, err := inst.Insert("user", c.data); !errors.Is(err, c.err) {
+				t.Fatalf("err = %v, want %v", err, c.err)
+			}
+			assertStatements(t, rec, c.sql, c.args)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	cases := []struct {
+		name string
+		data map[string]interface{}
+		exp  interface{}
+		sql  string
+		args []interface{}
+	}{
+		{
+			name: "set before where",
+			data: map[string]interface{}{"Name": "sam", "Age": 18},
+			exp:  map[string]interface{}{"ID = ?": 7},
+			sql:  "UPDATE `user` SET `Age`=?, `Name`=?  WHERE (ID = ?)",
+			args: []interface{}{int64(18), "sam", int64(7)},
+		},
+		{
+			name: "expr and slice",
+			data: map[string]interface{}{"LoginTimes": Expr("`LoginTimes` + ?", 1), "State": 2},
+			exp:  map[string]interface{}{"ID IN (?)": []int64{1, 2}},
+			sql:  "UPDATE `user` SET `LoginTimes`=`LoginTimes` + ?, `State`=?  WHERE (ID IN (?, ?))",
+			args: []interface{}{int64(1), int64(2), int64(1), int64(2)},
+		},
+		{
+			name: "null",
+			data: map[string]interface{}{"Nick": NullString{}, "Deleted": nil},
+			exp:  map[string]interface{}{"Name = ?": "o'neil"},
+			sql:  "UPDATE `user` SET `Deleted`=?, `Nick`=?  WHERE (Name = ?)",
+			args: []interface{}{nil, nil, "o'neil"},
+		},
+		{
+			name: "all rows",
+			data: map[string]interface{}{"State": 0},
+			exp:  AllRows(),
+			sql:  "UPDATE `user` SET `State`=? ",
+			args: []interface{}{int64(0)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			if _, err := inst.Update("user", c.data, c.exp); err != nil {
+				t.Fatal(err)
+			}
+			assertStatements(t, rec, c.sql, c.args)
+		})
+	}
+}
+
+func TestBatchInsertByLimit(t *testing.T) {
+	tooMany := make([]interface{}, maxBatchLimit+1)
+	for i := range tooMany {
+		tooMany[i] = []interface{}{i}
+	}
+
+	cases := []struct {
+		name    string
+		columns []string
+		params  []interface{}
+		sql     string
+		args    []interface{}
+		err     bool
+	}{
+		{
+			name:    "rows",
+			columns: []string{"Name", "Age"},
+			params:  []interface{}{[]interface{}{"o'neil", 18}, []string{"sam", "20"}},
+			sql:     "INSERT INTO `user` (`Name`,`Age`) VALUES (?,?),(?,?)",
+			args:    []interface{}{"o'neil", int64(18), "sam", "20"},
+		},
+		{
+			name:    "expr and null",
+			columns: []string{"Name", "Created"},
+			params:  []interface{}{[]interface{}{nil, Expr("NOW()")}, []interface{}{NullString{}, Expr("FROM_UNIXTIME(?)", 0)}},
+			sql:     "INSERT INTO `user` (`Name`,`Created`) VALUES (?,NOW()),(?,FROM_UNIXTIME(?))",
+			args:    []interface{}{nil, nil, int64(0)},
+		},
+		{name: "not slice", columns: []string{"Name"}, params: []interface{}{"sam"}, err: true},
+		{name: "length mismatch", columns: []string{"Name", "Age"}, params: []interface{}{[]interface{}{"sam"}}, err: true},
+		{name: "too many", columns: []string{"Age"}, params: tooMany, err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			_, _, err := inst.batchInsertByLimit(context.Background(), c.columns, c.params, "user", nil)
+			if (err != nil) != c.err {
+				t.Fatalf("err = %v, want error %v", err, c.err)
+			}
+			assertStatements(t, rec, c.sql, c.args)
+		})
+	}
+}
+
+// 校验驱动收到的语句，query 为空时表示不应执行任何语句
+func assertStatements(t *testing.T, rec *fakeRecorder, query string, args []interface{}) {
+	t.Helper()
+
+	statements := rec.Statements()
+	if query == "" {
+		if len(statements) != 0 {
+			t.Fatalf("got %d statements, want none", len(statements))
+		}
+		return
+	}
+	if len(statements) != 1 {
+		t.Fatalf("got %d statements, want 1", len(statements))
+	}
+	if statements[0].query != query {
+		t.Errorf("sql = %q, want %q", statements[0].query, query)
+	}
+	if !reflect.DeepEqual(statements[0].args, args) {
+		t.Errorf("args = %#v, want %#v", statements[0].args, args)
+	}
+}
+
+func TestFullTableGuard(t *testing.T) {
+	data := map[string]interface{}{"State": 0}
+	cases := []struct {
+		name string
+		exp  interface{}
+		sql  string
+		err  error
+	}{
+		{name: "nil", exp: nil, err: ErrFullTableWrite},
+		{name: "empty map", exp: map[string]interface{}{}, err: ErrFullTableWrite},
+		{name: "empty join", exp: map[string]map[string]interface{}{"AND": {}}, err: ErrFullTableWrite},
+		{name: "all rows", exp: AllRows(), sql: "DELETE FROM `user` "},
+		{name: "condition", exp: map[string]interface{}{"ID = ?": 1}, sql: "DELETE FROM `user`  WHERE (ID = ?)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			if _, err := inst.Update("user", data, c.exp); !errors.Is(err, c.err) {
+				t.Fatalf("update err = %v, want %v", err, c.err)
+			}
+			if _, err := inst.Delete("user", c.exp); !errors.Is(err, c.err) {
+				t.Fatalf("delete err = %v, want %v", err, c.err)
+			}
+
+			statements := rec.Statements()
+			if c.err != nil {
+				if len(statements) != 0 {
+					t.Fatalf("got %d statements, want none", len(statements))
+				}
+				return
+			}
+			if len(statements) != 2 || statements[1].query != c.sql {
+				t.Fatalf("got statements %+v, want delete %q", statements, c.sql)
+			}
+		})
+	}
+}
@@ -1,53 +1,206 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"sync"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+const (
+	DefaultInstance = "default" // 默认实例名称，Init 及包级函数使用该实例
 )
 
 var (
-	DB      *sql.DB
-	dbMutex sync.Mutex
+	DB        *sql.DB
+	dbMutex   sync.RWMutex
+	instances = make(map[string]*Instance)
 )
 
+// 实例选项
+type Options struct {
+	MaxOpenConns         int           // 最大连接数，默认1000，同时作用于主库和从库
+	MaxIdleConns         int           // 最大空闲连接数，默认200，同时作用于主库和从库
+	ConnMaxLifetime      time.Duration // 连接最长存活时间，默认不限制
+	ConnMaxIdleTime      time.Duration // 连接最长空闲时间，默认不限制
+	PingTimeout          time.Duration // 初始化时 ping 的超时，主库默认不限制，从库默认3s
+	QueryTimeout         time.Duration // 默认语句超时，可通过 WithQueryTimeout 按调用覆盖，默认不限制
+	KillOnCancel         bool          // ctx 取消或超时时通过另一个连接 KILL QUERY，使服务端同时停止执行(每条语句额外查询一次连接ID)，仅作用于执行阶段，不含读取查询结果
+	HealthCheckInterval  time.Duration // 主库健康检查间隔，默认10s，<0 时不检查
+	Replicas             []Replica     // 从库，配置后 SelectWhere、SelectBySql、Count、IsExist 路由至从库，事务内始终使用主库
+	Balancer             Balancer      // 从库负载均衡策略，默认轮询
+	ReplicaCheckInterval time.Duration // 从库健康检查间隔，默认5s
+	Logger               Logger        // 日志，默认使用 SetLogger 指定的日志(github.com/hzxgo/log)
+	LogLevel             LogLevel      // 最低日志级别，默认 LevelInfo
+	DisableStatementLog  bool          // 不记录执行成功的语句，执行失败的语句仍以 LevelError 记录
+	SlowThreshold        time.Duration // 慢查询阈值，超过时回调 OnSlowQuery 或以 LevelWarn 记录，默认不开启
+	Hooks                []Hook        // 语句钩子，按顺序执行，也可通过 AddHook 添加
+	Tracer               Tracer        // 链路追踪，为每条语句、事务及 BatchInsert 生成 span
+	DisableMetrics       bool          // 不统计语句耗时及错误，连接池指标不受影响
+	Retry                RetryPolicy   // 重试策略，默认不重试
+	SensitiveColumns     []string      // 敏感字段名模式(path.Match，不区分大小写)，如 *password*，其值在日志、链路追踪及错误信息中脱敏
+
+	// 健康状态变化时回调，如由正常变为异常
+	OnHealthChange func(name string, old HealthState, status HealthStatus)
+
+	// 慢查询回调，未指定时以 LevelWarn 记录日志
+	OnSlowQuery func(ctx context.Context, slow SlowQuery)
+}
+
 // ---------------------------------------------------------------------------------------------------------------------
 
-// 初始化 MySQL，仅支持一个实例
+// 初始化默认实例的 MySQL
 func Init(dataSource string) error {
-	dbMutex.Lock()
-	defer dbMutex.Unlock()
+	_, err := Register(DefaultInstance, dataSource, nil)
+	return err
+}
 
-	tmpDB, err := sql.Open("mysql", dataSource)
-	if err != nil {
-		return err
+// 初始化默认实例的 MySQL，读操作路由至从库
+func InitWithReplicas(dataSource string, replicas ...string) error {
+	opts := &Options{Replicas: make([]Replica, 0, len(replicas))}
+	for _, replica := range replicas {
+		opts.Replicas = append(opts.Replicas, Replica{DataSource: replica})
 	}
 
-	tmpDB.SetMaxOpenConns(1000)
-	tmpDB.SetMaxIdleConns(200)
+	_, err := Register(DefaultInstance, dataSource, opts)
+	return err
+}
+
+// 注册命名实例，同名实例已存在时替换并关闭旧实例(旧实例中已开始的语句会执行完毕)，可安全地重复调用
+// 主库连接失败时返回错误；从库连接失败时仅将其剔除，由健康检查在恢复后重新加入
+func Register(name, dataSource string, opts *Options) (*Instance, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
 
-	if err := tmpDB.Ping(); err != nil {
+	tmpDB, err := openDB(dataSource, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if err := pingTimeout(tmpDB, opts.PingTimeout); err != nil {
 		_ = tmpDB.Close()
-		return err
-	} else {
+		return nil, err
+	}
+
+	inst := newInstance(name, tmpDB, opts)
+	inst.recordHealth(time.Since(start), nil)
+	if err := inst.openReplicas(opts); err != nil {
+		_ = inst.Close()
+		return nil, err
+	}
+	inst.startHealthCheck()
+
+	dbMutex.Lock()
+	old := instances[name]
+	instances[name] = inst
+	if name == DefaultInstance {
 		DB = tmpDB
 	}
+	dbMutex.Unlock()
+
+	if old != nil {
+		_ = old.Close()
+	}
+
+	return inst, nil
+}
+
+// 注销并关闭命名实例
+func Unregister(name string) error {
+	dbMutex.Lock()
+	inst, ok := instances[name]
+	delete(instances, name)
+	dbMutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return inst.Close()
+}
+
+// 获取命名实例，未注册时返回的实例所有操作均返回错误
+func Use(name string) *Instance {
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
 
-	return nil
+	if inst, ok := instances[name]; ok {
+		return inst
+	}
+	if name == DefaultInstance && DB != nil {
+		return newInstance(name, DB, &Options{})
+	}
+	return &Instance{name: name}
+}
+
+// 获取默认实例
+func Default() *Instance {
+	return Use(DefaultInstance)
 }
 
 // 获取DB
 func GetDB() *sql.DB {
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+
 	return DB
 }
 
-// 释放资源
+// 释放资源：立即关闭所有实例
+//
+// Deprecated: 使用 Shutdown，等待正在执行的语句和事务结束后再关闭
 func FreeDB() {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 
-	if DB != nil {
+	closed := false
+	for name, inst := range instances {
+		if inst.db == DB {
+			closed = true
+		}
+		_ = inst.Close()
+		delete(instances, name)
+	}
+	if DB != nil && !closed {
 		_ = DB.Close()
 	}
+	DB = nil
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 打开连接池
+func openDB(dataSource string, opts *Options) (*sql.DB, error) {
+	connector, err := newConnector(mysqldriver.MySQLDriver{}, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+
+	maxOpenConns, maxIdleConns := 1000, 200
+	if opts.MaxOpenConns > 0 {
+		maxOpenConns = opts.MaxOpenConns
+	}
+	if opts.MaxIdleConns > 0 {
+		maxIdleConns = opts.MaxIdleConns
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// ping，timeout<=0 时不限制
+func pingTimeout(db *sql.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return db.Ping()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return db.PingContext(ctx)
 }
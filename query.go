@@ -2,37 +2,47 @@ package mysql
 
 import (
 	"fmt"
+	"time"
 )
 
 type Query struct {
 	Sql        string `db:"-" json:"-"`
 	Where      string `db:"-" json:"-"`
 	AfterWhere string `db:"-" json:"-"`
+
+	allowed []string      `db:"-" json:"-"` // 排序字段白名单，为空时不限制
+	timeout time.Duration `db:"-" json:"-"` // MAX_EXECUTION_TIME 提示，为0时使用实例或ctx中的语句超时
+	table   string        `db:"-" json:"-"` // Form 指定的表名，用于日志等
+	err     error         `db:"-" json:"-"` // 构建过程中的错误，由 SelectWhere 返回
 }
 
 func (q *Query) Form(tableName string) *Query {
-	q.Sql = fmt.Sprintf("%s FROM `%s`", q.Sql, tableName)
+	q.table = tableName
+	q.Sql = fmt.Sprintf("%s FROM %s", q.Sql, QuoteIdentifier(tableName))
 	return q
 }
 
 func (q *Query) LeftJoin(tableName, on string) *Query {
-	q.Sql = fmt.Sprintf("%s LEFT JOIN (`%s`) ON (%s)", q.Sql, tableName, on)
+	q.Sql = fmt.Sprintf("%s LEFT JOIN (%s) ON (%s)", q.Sql, QuoteIdentifier(tableName), on)
 	return q
 }
 
-func (q *Query) OrderBy(field string) *Query {
-	q.AfterWhere = fmt.Sprintf("%s ORDER BY `%s`", q.AfterWhere, field)
+// 限制 OrderBy/OrderAsc/OrderDesc 可使用的字段，用于接收客户端传入的排序字段
+func (q *Query) Allow(fields ...string) *Query {
+	q.allowed = append(q.allowed, fields...)
 	return q
 }
 
+func (q *Query) OrderBy(field string) *Query {
+	return q.order(field, "")
+}
+
 func (q *Query) OrderAsc(field string) *Query {
-	q.AfterWhere = fmt.Sprintf("%s ORDER BY `%s` ASC", q.AfterWhere, field)
-	return q
+	return q.order(field, " ASC")
 }
 
 func (q *Query) OrderDesc(field string) *Query {
-	q.AfterWhere = fmt.Sprintf("%s ORDER BY `%s` DESC", q.AfterWhere, field)
-	return q
+	return q.order(field, " DESC")
 }
 
 func (q *Query) Limit(limit uint64) *Query {
@@ -45,8 +55,19 @@ func (q *Query) LimitPage(offset, limit uint64) *Query {
 	return q
 }
 
+// 指定该查询的 MAX_EXECUTION_TIME 提示
+func (q *Query) Timeout(d time.Duration) *Query {
+	q.timeout = d
+	return q
+}
+
 func (q *Query) Combination() string {
-	cmd := q.Sql
+	return q.combine(q.timeout)
+}
+
+// 拼接语句，timeout 为 MAX_EXECUTION_TIME 提示
+func (q *Query) combine(timeout time.Duration) string {
+	cmd := addMaxExecutionTime(q.Sql, timeout)
 	if q.Where != "" {
 		cmd = cmd + q.Where
 
@@ -56,3 +77,14 @@ func (q *Query) Combination() string {
 	}
 	return cmd
 }
+
+func (q *Query) order(field, direction string) *Query {
+	if err := ValidateIdentifier(field, q.allowed...); err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	q.AfterWhere = fmt.Sprintf("%s ORDER BY %s%s", q.AfterWhere, QuoteIdentifier(field), direction)
+	return q
+}
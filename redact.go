@@ -0,0 +1,298 @@
+package mysql
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// 脱敏后的值
+	redactedValue = "***"
+	// 字符串或数字字面量
+	literalPattern = "'(?:[^'\\\\]|\\\\.|'')*'|\"(?:[^\"\\\\]|\\\\.|\"\")*\"|-?\\d+(?:\\.\\d+)?"
+)
+
+var (
+	whereColumnRegexp = regexp.MustCompile("^[\\s(]*([`\\w$.]+)")
+	// 原生SQL中 ? 之前的比较，如 `Password` = ?
+	placeholderColumnRegexp = regexp.MustCompile("(?i)`?([\\w$]+)`?\\s*(?:=|<>|!=|<=>|<=|>=|<|>|\\bLIKE)\\s*$")
+	// 原生SQL中的 字段=字面量，如 `Password`='123456'
+	assignLiteralRegexp = regexp.MustCompile("(`?[\\w$]+`?\\s*=\\s*)(" + literalPattern + ")")
+	// 原生SQL中 INSERT 的 VALUES 值本身为字面量
+	valueLiteralRegexp = regexp.MustCompile("^(?:" + literalPattern + ")$")
+	// INSERT/REPLACE 语句的字段列表，匹配至 VALUES 的第一个 (
+	insertValuesRegexp = regexp.MustCompile("(?is)\\b(?:INSERT|REPLACE)\\b[^(]*?\\(([^)]*)\\)\\s*VALUES?\\s*\\(")
+	// 唯一键冲突信息中的值，MySQL 不转义其中的引号
+	duplicateEntryRegexp = regexp.MustCompile(`(?s)Duplicate entry '.*' for key`)
+)
+
+// 保留底层错误的脱敏错误
+type redactedError struct {
+	msg string
+	err error
+}
+
+// INSERT 语句 VALUES 中的一个值，[start, end) 为其在语句中的位置
+type valueSpan struct {
+	column     string
+	start, end int
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// 字段是否敏感：db 标签标记为 sensitive，或匹配 Options.SensitiveColumns(不区分大小写)
+// 无法推断所属字段(column 为空)时，只要配置了敏感字段即视为敏感
+func (s *session) isSensitive(st *statement, column string) bool {
+	if column == "" {
+		return s.hasSensitive(st)
+	}
+	if st.sensitive[column] {
+		return true
+	}
+	if s.inst == nil {
+		return false
+	}
+
+	column = strings.ToLower(column)
+	for _, pattern := range s.inst.opts.SensitiveColumns {
+		if ok, _ := path.Match(strings.ToLower(pattern), column); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否可能存在敏感字段
+func (s *session) hasSensitive(st *statement) bool {
+	return len(st.sensitive) > 0 || (s.inst != nil && len(s.inst.opts.SensitiveColumns) > 0)
+}
+
+// 敏感字段的参数替换为 ***，未脱敏时返回原参数
+func (s *session) redactArgs(st *statement, args []interface{}) []interface{} {
+	if !s.hasSensitive(st) {
+		return args
+	}
+
+	var redacted []interface{}
+	columns := argColumns(st)
+	for i := range args {
+		if !s.isSensitive(st, columnAt(columns, args, i)) {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]interface{}(nil), args...)
+		}
+		redacted[i] = redactedValue
+	}
+
+	if redacted == nil {
+		return args
+	}
+	return redacted
+}
+
+// 原生SQL中敏感字段的字面量替换为 ***
+func (s *session) redactSQL(st *statement, cmd string) string {
+	if !s.hasSensitive(st) {
+		return cmd
+	}
+
+	spans := insertValueSpans(cmd)
+	for i := len(spans) - 1; i >= 0; i-- {
+		span := spans[i]
+		value := cmd[span.start:span.end]
+		start := span.start + len(value) - len(strings.TrimLeft(value, " \t\r\n"))
+		end := span.start + len(strings.TrimRight(value, " \t\r\n"))
+		if start < end && valueLiteralRegexp.MatchString(cmd[start:end]) && s.isSensitive(st, span.column) {
+			cmd = cmd[:start] + "'" + redactedValue + "'" + cmd[end:]
+		}
+	}
+
+	return assignLiteralRegexp.ReplaceAllStringFunc(cmd, func(match string) string {
+		parts := assignLiteralRegexp.FindStringSubmatch(match)
+		column := strings.Trim(strings.TrimRight(parts[1], " \t\r\n="), "`")
+		if !s.isSensitive(st, column) {
+			return match
+		}
+		return parts[1] + "'" + redactedValue + "'"
+	})
+}
+
+// 错误信息中出现的敏感参数替换为 ***，返回的错误仍可通过 errors.Is/As 判断
+// MySQL 会截断过长的值，组合唯一键的值以 - 连接，因此语句含敏感值时 Duplicate entry '...' 整段替换
+func (s *session) redactError(st *statement, err error) error {
+	if err == nil || !s.hasSensitive(st) {
+		return err
+	}
+
+	msg := err.Error()
+	redacted := msg
+	sensitive := s.redactSQL(st, st.sql) != st.sql
+	columns := argColumns(st)
+	for i := range st.args {
+		if !s.isSensitive(st, columnAt(columns, st.args, i)) {
+			continue
+		}
+		sensitive = true
+
+		var value string
+		switch v := st.args[i].(type) {
+		case string:
+			value = v
+		case []byte:
+			value = string(v)
+		default:
+			value = fmt.Sprint(v)
+		}
+		if value != "" {
+			redacted = strings.ReplaceAll(redacted, "'"+value+"'", "'"+redactedValue+"'")
+		}
+	}
+	if sensitive {
+		redacted = duplicateEntryRegexp.ReplaceAllString(redacted, "Duplicate entry '"+redactedValue+"' for key")
+	}
+
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 各参数所属的字段，原生SQL按 INSERT 的字段列表或 ? 之前的比较推断
+// 如 INSERT INTO `user` (`Name`,`Password`) VALUES (?,?)、UPDATE `user` SET `Password`=? 中的 Password
+func argColumns(st *statement) []string {
+	if st.columns != nil || len(st.args) == 0 {
+		return st.columns
+	}
+
+	spans := insertValueSpans(st.sql)
+	columns := make([]string, 0, len(st.args))
+	var quote byte
+	for i := 0; i < len(st.sql); i++ {
+		c := st.sql[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			column, ok := spanColumn(spans, i)
+			if !ok {
+				if match := placeholderColumnRegexp.FindStringSubmatch(st.sql[:i]); match != nil {
+					column = match[1]
+				}
+			}
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// INSERT 语句 VALUES 中各值及其所属字段，非 INSERT 语句返回 nil
+// VALUES 之后的 ON DUPLICATE KEY UPDATE 等部分不在其中
+func insertValueSpans(cmd string) []valueSpan {
+	loc := insertValuesRegexp.FindStringSubmatchIndex(cmd)
+	if loc == nil {
+		return nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(cmd[loc[2]:loc[3]], ",") {
+		columns = append(columns, identifierName(column))
+	}
+
+	var spans []valueSpan
+	var quote byte
+	depth, index, start := 0, 0, 0
+	for i := loc[1] - 1; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+			if depth == 1 {
+				index, start = 0, i+1
+			}
+		case c == ')' || (c == ',' && depth == 1):
+			if depth == 1 && index < len(columns) {
+				spans = append(spans, valueSpan{column: columns[index], start: start, end: i})
+			}
+			if c == ',' {
+				index, start = index+1, i+1
+			} else {
+				depth--
+			}
+		case depth == 0 && c != ',' && c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			return spans
+		}
+	}
+	return spans
+}
+
+// 位置 pos 所在 VALUES 值的字段
+func spanColumn(spans []valueSpan, pos int) (string, bool) {
+	for _, span := range spans {
+		if pos >= span.start && pos < span.end {
+			return span.column, true
+		}
+	}
+	return "", false
+}
+
+// 第 i 个参数所属的字段，字段个数与参数个数不一致时无法对应，返回空
+func columnAt(columns []string, args []interface{}, i int) string {
+	if len(columns) == len(args) {
+		return columns[i]
+	}
+	return ""
+}
+
+// 为 n 个参数记录所属字段
+func appendColumn(columns []string, column string, n int) []string {
+	for i := 0; i < n; i++ {
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// where 条件中的字段名，如 "`user`.`Password` = ?" 中的 Password
+func whereColumn(key string) string {
+	match := whereColumnRegexp.FindStringSubmatch(key)
+	if match == nil {
+		return ""
+	}
+
+	return identifierName(match[1])
+}
+
+// 去掉表名及反引号的字段名，如 "`user`.`Password`" 中的 Password
+func identifierName(column string) string {
+	column = strings.TrimSpace(column)
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, "`")
+}
@@ -0,0 +1,214 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestRedactError(t *testing.T) {
+	long := "0123456789012345678901234567890123456789012345678901234567890123"
+	cases := []struct {
+		name string
+		st   *statement
+		msg  string
+		want string
+	}{
+		{
+			name: "exact value",
+			st:   &statement{sql: "INSERT", args: []interface{}{"sam", "s3cret"}, columns: []string{"Name", "Password"}, sensitive: map[string]bool{"Password": true}},
+			msg:  "Duplicate entry 's3cret' for key 'user.idx_password'",
+			want: "Duplicate entry '***' for key 'user.idx_password'",
+		},
+		{
+			name: "truncated value",
+			st:   &statement{sql: "INSERT", args: []interface{}{long}, columns: []string{"Token"}, sensitive: map[string]bool{"Token": true}},
+			msg:  "Duplicate entry '" + long[:32] + "' for key 'user.idx_token'",
+			want: "Duplicate entry '***' for key 'user.idx_token'",
+		},
+		{
+			name: "composite key",
+			st:   &statement{sql: "INSERT", args: []interface{}{"sam", "it's"}, columns: []string{"Name", "Password"}, sensitive: map[string]bool{"Password": true}},
+			msg:  "Duplicate entry 'sam-it's' for key 'user.idx_name_password'",
+			want: "Duplicate entry '***' for key 'user.idx_name_password'",
+		},
+		{
+			name: "raw sql literal",
+			st:   &statement{sql: "UPDATE `user` SET `Password`='s3cret' WHERE ID=1", sensitive: map[string]bool{"Password": true}},
+			msg:  "Duplicate entry 's3cret' for key 'user.idx_password'",
+			want: "Duplicate entry '***' for key 'user.idx_password'",
+		},
+		{
+			name: "no sensitive value",
+			st:   &statement{sql: "INSERT", args: []interface{}{"sam"}, columns: []string{"Name"}, sensitive: map[string]bool{"Password": true}},
+			msg:  "Duplicate entry 'sam' for key 'user.idx_name'",
+			want: "Duplicate entry 'sam' for key 'user.idx_name'",
+		},
+	}
+
+	inst, _ := newFakeInstance(t, nil)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cause := &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: c.msg}
+			err := inst.redactError(c.st, translateError(cause))
+			if want := "Error 1062: " + c.want; err.Error() != want {
+				t.Errorf("got %q, want %q", err.Error(), want)
+			}
+
+			var myErr *mysqldriver.MySQLError
+			if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &myErr) {
+				t.Errorf("redacted error lost its cause: %v", err)
+			}
+		})
+	}
+}
+
+// 内存日志，记录每条日志的字段
+type memoryLogger struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+}
+
+func (l *memoryLogger) Log(_ context.Context, _ LogLevel, msg string, fields ...Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := map[string]interface{}{"msg": msg}
+	for _, field := range fields {
+		entry[field.Key] = field.Value
+	}
+	l.entries = append(l.entries, entry)
+}
+
+// 最后一条日志的字段
+func (l *memoryLogger) last() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) == 0 {
+		return nil
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+// 为语句增加租户字段的钩子
+type tenantHook struct {
+	rewrite bool // 为 false 时只添加参数，不改写语句
+}
+
+func (h tenantHook) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	if h.rewrite {
+		event.Statement = strings.Replace(event.Statement, "(`", "(`TenantID`,`", 1)
+		event.Statement = strings.Replace(event.Statement, "VALUES(", "VALUES(?,", 1)
+	}
+	event.Args = append([]interface{}{"tenant-7"}, event.Args...)
+	return ctx, nil
+}
+
+func (tenantHook) AfterQuery(context.Context, *QueryEvent) {}
+
+func TestRedactHookArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		hook tenantHook
+		sql  string
+		args []interface{}
+	}{
+		{
+			name: "statement rewritten",
+			hook: tenantHook{rewrite: true},
+			sql:  "INSERT INTO `user` (`TenantID`,`Name`,`Password`) VALUES(?,?,?)",
+			args: []interface{}{"tenant-7", "sam", redactedValue},
+		},
+		{
+			name: "args mismatch",
+			sql:  "INSERT INTO `user` (`Name`,`Password`) VALUES(?,?)",
+			args: []interface{}{redactedValue, redactedValue, redactedValue},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logger := &memoryLogger{}
+			inst, rec := newFakeInstance(t, &Options{Logger: logger, SensitiveColumns: []string{"*password*"}, Hooks: []Hook{c.hook}})
+			if _, err := inst.Insert("user", map[string]interface{}{"Name": "sam", "Password": "s3cret"}); err != nil {
+				t.Fatal(err)
+			}
+
+			statements := rec.Statements()
+			if len(statements) != 1 || statements[0].query != c.sql || statements[0].args[2] != "s3cret" {
+				t.Fatalf("got statements %+v", statements)
+			}
+			if args := logger.last()["args"]; !reflect.DeepEqual(args, c.args) {
+				t.Errorf("logged args %v, want %v", args, c.args)
+			}
+		})
+	}
+}
+
+func TestRedactRawSQL(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		args []interface{}
+		want []interface{}
+		log  string
+	}{
+		{
+			name: "insert placeholders",
+			sql:  "INSERT INTO user (Name, `Password`) VALUES (?,?), (?, UPPER(?))",
+			args: []interface{}{"sam", "s3cret", "tom", "pa55"},
+			want: []interface{}{"sam", redactedValue, "tom", redactedValue},
+		},
+		{
+			name: "insert literals",
+			sql:  "INSERT INTO user (Name,Password,Age) VALUES ('sam', 'it''s,(x)', 18)",
+			log:  "INSERT INTO user (Name,Password,Age) VALUES ('sam', '***', 18)",
+		},
+		{
+			name: "on duplicate key update",
+			sql:  "INSERT INTO user (Name) VALUES (?) ON DUPLICATE KEY UPDATE Password=?, Age=Age+1",
+			args: []interface{}{"sam", "s3cret"},
+			want: []interface{}{"sam", redactedValue},
+		},
+		{
+			name: "update",
+			sql:  "UPDATE user SET `Password` = ?, Name='sam' WHERE ID = ?",
+			args: []interface{}{"s3cret", 1},
+			want: []interface{}{redactedValue, 1},
+		},
+		{
+			name: "unknown column",
+			sql:  "SELECT * FROM user WHERE ID IN (?) AND Name = ?",
+			args: []interface{}{1, "sam"},
+			want: []interface{}{redactedValue, "sam"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logger := &memoryLogger{}
+			inst, _ := newFakeInstance(t, &Options{Logger: logger, SensitiveColumns: []string{"*password*"}})
+			if _, err := inst.UpdateBySqlContext(context.Background(), c.sql, c.args...); err != nil {
+				t.Fatal(err)
+			}
+
+			entry := logger.last()
+			if c.want != nil && !reflect.DeepEqual(entry["args"], c.want) {
+				t.Errorf("logged args %v, want %v", entry["args"], c.want)
+			}
+			want := c.log
+			if want == "" {
+				want = c.sql
+			}
+			if entry["statement"] != want {
+				t.Errorf("logged statement %q, want %q", entry["statement"], want)
+			}
+		})
+	}
+}
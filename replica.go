@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// 从库负载均衡策略
+type Balancer int
+
+const (
+	BalanceRoundRobin Balancer = iota // 轮询
+	BalanceWeighted                   // 按权重轮询
+)
+
+const (
+	defaultReplicaCheckInterval = 5 * time.Second // 默认从库健康检查间隔
+	defaultReplicaPingTimeout   = 3 * time.Second // 未设置 PingTimeout 时从库初始化 ping 的超时，从库不可达时不阻塞 Register
+)
+
+// 从库配置
+type Replica struct {
+	DataSource string
+	Weight     int // 权重，仅 BalanceWeighted 有效，<=0 时为1
+}
+
+// 从库
+type replica struct {
+	db      *sql.DB
+	weight  int
+	healthy int32 // 1: 正常，0: 已剔除
+}
+
+type primaryCtxKey struct{}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 标记ctx中的读操作强制使用主库，如写后立即读
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 是否强制使用主库
+func isUsePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	usePrimary, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return usePrimary
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&r.healthy, 1)
+	} else {
+		atomic.StoreInt32(&r.healthy, 0)
+	}
+}
+
+// 读操作路由：强制主库、无从库或从库均已剔除时使用主库
+func (i *Instance) route(ctx context.Context) Executor {
+	if isUsePrimary(ctx) {
+		return i.db
+	}
+	if r := i.pickReplica(); r != nil {
+		return r.db
+	}
+	return i.db
+}
+
+// 基于负载均衡策略选择一个正常的从库
+func (i *Instance) pickReplica() *replica {
+	healthy := make([]*replica, 0, len(i.replicas))
+	totalWeight := 0
+	for _, r := range i.replicas {
+		if r.isHealthy() {
+			healthy = append(healthy, r)
+			totalWeight += r.weight
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	n := atomic.AddUint64(&i.counter, 1) - 1
+	if i.balancer != BalanceWeighted {
+		return healthy[n%uint64(len(healthy))]
+	}
+
+	offset := int(n % uint64(totalWeight))
+	for _, r := range healthy {
+		if offset < r.weight {
+			return r
+		}
+		offset -= r.weight
+	}
+	return healthy[len(healthy)-1]
+}
+
+// 定时检查从库，ping失败的从库会被剔除，恢复后重新加入
+func (i *Instance) checkReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			for _, r := range i.replicas {
+				r.setHealthy(pingTimeout(r.db, interval) == nil)
+			}
+		}
+	}
+}
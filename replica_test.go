@@ -0,0 +1,164 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 为实例添加使用测试驱动的从库，按 weights 依次创建
+func addFakeReplicas(t *testing.T, inst *Instance, balancer Balancer, weights ...int) []*fakeRecorder {
+	t.Helper()
+
+	recs := make([]*fakeRecorder, 0, len(weights))
+	for _, weight := range weights {
+		db, rec := newFakeDB(t)
+		r := &replica{db: db, weight: weight}
+		r.setHealthy(true)
+		inst.replicas = append(inst.replicas, r)
+		recs = append(recs, rec)
+	}
+	inst.balancer = balancer
+	inst.reader = inst.route
+	return recs
+}
+
+// 各从库被选中的次数，-1 为未选中任何从库
+func pickCounts(inst *Instance, n int) map[int]int {
+	counts := make(map[int]int)
+	for k := 0; k < n; k++ {
+		picked := -1
+		if r := inst.pickReplica(); r != nil {
+			for idx, item := range inst.replicas {
+				if item == r {
+					picked = idx
+				}
+			}
+		}
+		counts[picked]++
+	}
+	return counts
+}
+
+func TestPickReplica(t *testing.T) {
+	tests := []struct {
+		name     string
+		balancer Balancer
+		weights  []int
+		ejected  []int
+		want     map[int]int // 8 次选择中各从库被选中的次数
+	}{
+		{"round robin", BalanceRoundRobin, []int{3, 1}, nil, map[int]int{0: 4, 1: 4}},
+		{"weighted", BalanceWeighted, []int{3, 1}, nil, map[int]int{0: 6, 1: 2}},
+		{"weighted ejected", BalanceWeighted, []int{3, 1, 2}, []int{0}, map[int]int{1: 3, 2: 5}},
+		{"round robin ejected", BalanceRoundRobin, []int{1, 1, 1}, []int{1}, map[int]int{0: 4, 2: 4}},
+		{"all ejected", BalanceRoundRobin, []int{1, 1}, []int{0, 1}, map[int]int{-1: 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst, _ := newFakeInstance(t, nil)
+			addFakeReplicas(t, inst, tt.balancer, tt.weights...)
+			for _, idx := range tt.ejected {
+				inst.replicas[idx].setHealthy(false)
+			}
+
+			if got := pickCounts(inst, 8); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	replicas := addFakeReplicas(t, inst, BalanceRoundRobin, 1, 1)
+	ctx := context.Background()
+
+	count := func(ctx context.Context, s *session) {
+		t.Helper()
+		if _, err := s.CountContext(ctx, "user", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	statements := func() []int {
+		return []int{len(rec.Statements()), len(replicas[0].Statements()), len(replicas[1].Statements())}
+	}
+	assertStatements := func(want ...int) {
+		t.Helper()
+		if got := statements(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got statements on primary and replicas %v, want %v", got, want)
+		}
+	}
+
+	for k := 0; k < 4; k++ {
+		count(ctx, &inst.session)
+	}
+	assertStatements(0, 2, 2)
+
+	// 强制主库
+	count(UsePrimary(ctx), &inst.session)
+	assertStatements(1, 2, 2)
+
+	// 写操作
+	if _, err := inst.InsertContext(ctx, "user", map[string]interface{}{"Name": "sam"}); err != nil {
+		t.Fatal(err)
+	}
+	assertStatements(2, 2, 2)
+
+	// 事务内，包括通过 ctx 携带的事务
+	err := inst.WithTx(ctx, nil, func(tx *Tx) error {
+		count(ctx, &tx.session)
+		count(tx.Context(), &inst.session)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatements(4, 2, 2)
+
+	// 从库全部剔除后回落至主库
+	for _, r := range inst.replicas {
+		r.setHealthy(false)
+	}
+	count(ctx, &inst.session)
+	assertStatements(5, 2, 2)
+}
+
+func TestCheckReplicas(t *testing.T) {
+	inst, _ := newFakeInstance(t, nil)
+	replicas := addFakeReplicas(t, inst, BalanceRoundRobin, 1, 1)
+	t.Cleanup(func() { _ = inst.Close() })
+
+	waitHealthy := func(want ...bool) {
+		t.Helper()
+		var got []bool
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			got = got[:0]
+			for _, r := range inst.replicas {
+				got = append(got, r.isHealthy())
+			}
+			if reflect.DeepEqual(got, want) {
+				return
+			}
+		}
+		t.Fatalf("got replicas healthy %v, want %v", got, want)
+	}
+
+	replicas[0].failWith(func(string) error {
+		return errors.New("replica is down")
+	})
+	go inst.checkReplicas(5 * time.Millisecond)
+
+	// ping 失败时剔除，其他从库不受影响
+	waitHealthy(false, true)
+	if r := inst.pickReplica(); r != inst.replicas[1] {
+		t.Error("ejected replica should not be picked")
+	}
+
+	// 恢复后重新加入
+	replicas[0].failWith(nil)
+	waitHealthy(true, true)
+}
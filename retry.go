@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultRetryBaseDelay = 50 * time.Millisecond
+	defaultRetryMaxDelay  = time.Second
+)
+
+// 主从切换后原主库变为只读时返回的错误码
+const (
+	errNumOptionPreventsStatement = 1290 // --read-only 拒绝执行
+	errNumReadOnlyMode            = 1836 // super_read_only
+	errNumConnectionKilled        = 1927 // 连接被 KILL
+)
+
+// 重试策略：查询语句(SelectWhere、SelectBySql、Count、IsExist)在事务外失败时按策略重试，
+// 写操作仅在 ctx 通过 WithIdempotent 标记为幂等时重试
+type RetryPolicy struct {
+	MaxAttempts int                  // 最大执行次数(含首次)，<=1 表示不重试
+	BaseDelay   time.Duration        // 首次重试前的等待，之后按指数增长并加入随机抖动，默认 50ms
+	MaxDelay    time.Duration        // 最长等待，默认 1s
+	Classifier  func(err error) bool // 判断错误是否可重试，默认 IsRetryable
+}
+
+type idempotentCtxKey struct{}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 标记ctx中的写操作为幂等，失败时按 Options.Retry 重试
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentCtxKey{}, true)
+}
+
+// 默认的可重试错误：连接失效或重置、MySQL server has gone away(2006/2013)、连接被 KILL、死锁，
+// 以及主从切换后原主库只读(1290/1836)
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrConnectionLost) || errors.Is(err, ErrDeadlock) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case errNumServerGone, errNumServerLost, errNumDeadlock, errNumConnectionKilled,
+			errNumOptionPreventsStatement, errNumReadOnlyMode:
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 按策略执行 fn，write 为 true 时仅在 ctx 标记为幂等时重试；事务中不重试
+func (s *session) retry(ctx context.Context, write bool, fn func() error) error {
+	policy := s.retryPolicy()
+	if s.inTx() || policy.MaxAttempts <= 1 {
+		return fn()
+	}
+	if idempotent, _ := ctx.Value(idempotentCtxKey{}).(bool); write && !idempotent {
+		return fn()
+	}
+
+	classifier := policy.Classifier
+	if classifier == nil {
+		classifier = IsRetryable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= policy.MaxAttempts || !classifier(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *session) retryPolicy() RetryPolicy {
+	if s.inst == nil {
+		return RetryPolicy{}
+	}
+	return s.inst.opts.Retry
+}
+
+// 第 attempt 次失败后的等待：BaseDelay*2^(attempt-1)，不超过 MaxDelay，取其 [1/2, 1] 间的随机值
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base, limit := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if limit <= 0 {
+		limit = defaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
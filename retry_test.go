@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestRetry(t *testing.T) {
+	serverGone := &mysqldriver.MySQLError{Number: errNumServerGone, Message: "MySQL server has gone away"}
+	serverLost := &mysqldriver.MySQLError{Number: errNumServerLost, Message: "Lost connection to MySQL server during query"}
+	duplicate := &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry 'sam' for key 'user.idx_name'"}
+
+	cases := []struct {
+		name  string
+		ctx   context.Context
+		write bool
+		err   error
+		calls int
+	}{
+		{name: "read on server gone", err: serverGone, calls: 3},
+		{name: "read on server lost", err: serverLost, calls: 3},
+		{name: "read on bad conn", err: driver.ErrBadConn, calls: 3},
+		{name: "read on translated error", err: translateError(serverGone), calls: 3},
+		{name: "not retryable", err: duplicate, calls: 1},
+		{name: "write", write: true, err: serverGone, calls: 1},
+		{name: "idempotent write", ctx: WithIdempotent(context.Background()), write: true, err: serverGone, calls: 3},
+		{name: "canceled", ctx: canceledContext(), err: serverGone, calls: 1},
+	}
+
+	inst, _ := newFakeInstance(t, &Options{Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Microsecond}})
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := c.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			calls := 0
+			err := inst.retry(ctx, c.write, func() error {
+				calls++
+				return c.err
+			})
+			if err != c.err || calls != c.calls {
+				t.Errorf("got err=%v calls=%d, want %v and %d", err, calls, c.err, c.calls)
+			}
+		})
+	}
+
+	t.Run("success after retry", func(t *testing.T) {
+		calls := 0
+		err := inst.retry(context.Background(), false, func() error {
+			if calls++; calls < 2 {
+				return serverGone
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("got err=%v calls=%d, want nil and 2", err, calls)
+		}
+	})
+
+	t.Run("in tx", func(t *testing.T) {
+		tx, err := inst.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		calls := 0
+		_ = tx.retry(context.Background(), false, func() error {
+			calls++
+			return serverGone
+		})
+		if calls != 1 {
+			t.Errorf("got %d calls in tx, want 1", calls)
+		}
+	})
+}
+
+func TestRetryStatement(t *testing.T) {
+	serverGone := &mysqldriver.MySQLError{Number: errNumServerGone, Message: "MySQL server has gone away"}
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true, Retry: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Microsecond}})
+	n := 0
+	rec.failWith(func(string) error {
+		if n++; n == 1 {
+			return serverGone
+		}
+		return nil
+	})
+
+	if _, err := inst.Count("user", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(rec.Statements()); got != 2 {
+		t.Errorf("read ran %d times, want 2", got)
+	}
+
+	n = 0
+	if _, err := inst.Delete("user", map[string]interface{}{"ID = ?": 1}); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("write err = %v, want ErrConnectionLost", err)
+	}
+	if got := len(rec.Statements()); got != 3 {
+		t.Errorf("write ran %d times, want 1", got-2)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cases := []struct {
+		policy RetryPolicy
+		want   []time.Duration // 各次失败后的最长等待
+	}{
+		{
+			policy: RetryPolicy{},
+			want:   []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second},
+		},
+		{
+			policy: RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 25 * time.Millisecond},
+			want:   []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond, 25 * time.Millisecond},
+		},
+	}
+
+	for _, c := range cases {
+		for n, limit := range c.want {
+			for i := 0; i < 100; i++ {
+				if d := c.policy.backoff(n + 1); d < limit/2 || d > limit {
+					t.Fatalf("%+v backoff(%d) = %v, want in [%v, %v]", c.policy, n+1, d, limit/2, limit)
+				}
+			}
+		}
+	}
+}
+
+// 已取消的ctx
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,307 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// SQL执行器：*sql.DB 与 *sql.Tx 均实现了该接口
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// 语句
+type statement struct {
+	op        string // 操作类型：select、insert、update、delete、batch_insert、exec
+	table     string // 表名，原生SQL时为空
+	sql       string
+	args      []interface{}
+	columns   []string        // 各参数所属的字段，用于脱敏，原生SQL时为空
+	sensitive map[string]bool // 通过 db 标签标记为敏感的字段
+}
+
+// 会话：写操作通过 exec 执行，可以是连接池也可以是事务；读操作通过 reader 路由，为空时同样使用 exec
+type session struct {
+	exec   Executor
+	reader func(ctx context.Context) Executor
+	inst   *Instance       // 所属实例，提供超时等配置
+	connID int64           // 事务所在连接的ID，用于 KILL QUERY
+	ctx    context.Context // 不带 ctx 的方法所使用的上下文，事务中为携带当前事务的上下文
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 基于SQL查询
+func SelectBySql(cmd string, value ...interface{}) (*sql.Rows, error) {
+	return Default().SelectBySql(cmd, value...)
+}
+
+// 基于SQL查询，配置了从库时路由至从库
+func SelectBySqlContext(ctx context.Context, cmd string, value ...interface{}) (*sql.Rows, error) {
+	return Default().SelectBySqlContext(ctx, cmd, value...)
+}
+
+// 查询记录
+func SelectWhere(query *Query, exp interface{}) (*sql.Rows, error) {
+	return Default().SelectWhere(query, exp)
+}
+
+// 查询记录，配置了从库时路由至从库
+func SelectWhereContext(ctx context.Context, query *Query, exp interface{}) (*sql.Rows, error) {
+	return Default().SelectWhereContext(ctx, query, exp)
+}
+
+// 插入数据：支持 对象指针类型 和 Map 类型
+func Insert(tableName string, data interface{}) (int64, error) {
+	return Default().Insert(tableName, data)
+}
+
+// 插入数据：支持 对象指针类型 和 Map 类型，ctx 取消或超时时中止执行
+func InsertContext(ctx context.Context, tableName string, data interface{}) (int64, error) {
+	return Default().InsertContext(ctx, tableName, data)
+}
+
+// 基于SQL插入数据
+func InsertBySql(cmd string, value ...interface{}) (int64, error) {
+	return Default().InsertBySql(cmd, value...)
+}
+
+// 基于SQL插入数据，ctx 取消或超时时中止执行
+func InsertBySqlContext(ctx context.Context, cmd string, value ...interface{}) (int64, error) {
+	return Default().InsertBySqlContext(ctx, cmd, value...)
+}
+
+// 插入多条记录：支持 对象指针类型 和 Map 类型
+// 返回值：最后插入的id，插入的数量，错误信息
+func MInsert(tableName string, data ...interface{}) (int64, int64, error) {
+	return Default().MInsert(tableName, data...)
+}
+
+// 插入多条记录：支持 对象指针类型 和 Map 类型，ctx 取消或超时时中止执行
+func MInsertContext(ctx context.Context, tableName string, data ...interface{}) (int64, int64, error) {
+	return Default().MInsertContext(ctx, tableName, data...)
+}
+
+// 更新：基于exp表达式更新data数据
+func Update(tableName string, data interface{}, exp interface{}) (int64, error) {
+	return Default().Update(tableName, data, exp)
+}
+
+// 更新：基于exp表达式更新data数据，ctx 取消或超时时中止执行
+func UpdateContext(ctx context.Context, tableName string, data interface{}, exp interface{}) (int64, error) {
+	return Default().UpdateContext(ctx, tableName, data, exp)
+}
+
+// 基于SQL更新
+func UpdateBySql(cmd string, value ...interface{}) (int64, error) {
+	return Default().UpdateBySql(cmd, value...)
+}
+
+// 基于SQL更新，ctx 取消或超时时中止执行
+func UpdateBySqlContext(ctx context.Context, cmd string, value ...interface{}) (int64, error) {
+	return Default().UpdateBySqlContext(ctx, cmd, value...)
+}
+
+// 删除：基于exp表达式删除数据
+func Delete(tableName string, exp interface{}) (int64, error) {
+	return Default().Delete(tableName, exp)
+}
+
+// 删除：基于exp表达式删除数据，ctx 取消或超时时中止执行
+func DeleteContext(ctx context.Context, tableName string, exp interface{}) (int64, error) {
+	return Default().DeleteContext(ctx, tableName, exp)
+}
+
+// 批量插入数据
+func BatchInsert(tableName string, columns []string, params []interface{}) (int64, int64, error) {
+	return Default().BatchInsert(tableName, columns, params)
+}
+
+// 批量插入数据，ctx 取消或超时时中止执行
+func BatchInsertContext(ctx context.Context, tableName string, columns []string, params []interface{}) (int64, int64, error) {
+	return Default().BatchInsertContext(ctx, tableName, columns, params)
+}
+
+// 基于条件表达式判断数据是否存在
+func IsExist(tableName string, exp interface{}, field string, value string) (bool, error) {
+	return Default().IsExist(tableName, exp, field, value)
+}
+
+// 基于条件表达式判断数据是否存在，配置了从库时路由至从库
+func IsExistContext(ctx context.Context, tableName string, exp interface{}, field string, value string) (bool, error) {
+	return Default().IsExistContext(ctx, tableName, exp, field, value)
+}
+
+// 统计
+func Count(tableName string, exp interface{}) (int, error) {
+	return Default().Count(tableName, exp)
+}
+
+// 统计，配置了从库时路由至从库
+func CountContext(ctx context.Context, tableName string, exp interface{}) (int, error) {
+	return Default().CountContext(ctx, tableName, exp)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 实例的会话在ctx携带同一实例的事务(见 Tx.Context)时改用该事务，避免在事务外执行而等待事务自身持有的锁
+func (s *session) bind(ctx context.Context) *session {
+	if s.inst == nil || s.inTx() {
+		return s
+	}
+	if tx := s.inst.parentTx(ctx); tx != nil {
+		return &tx.session
+	}
+	return s
+}
+
+// 执行查询语句
+func (s *session) query(ctx context.Context, st *statement) (*sql.Rows, error) {
+	s = s.bind(ctx)
+	if s.exec == nil {
+		return nil, errDBNotInit
+	}
+
+	leave, err := s.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	// 返回的 rows 仍在使用 ctx，因此成功时不能在此处 cancel，由 WithTimeout 在超时后释放；失败时立即释放
+	if timeout := s.queryTimeout(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer func() {
+			if err != nil {
+				cancel()
+			}
+		}()
+	}
+
+	// 每次重试重新选择从库，剔除失效的从库后可路由至其他从库或主库
+	var rows *sql.Rows
+	err = s.withHooks(ctx, st, func(ctx context.Context) (int64, error) {
+		return -1, s.retry(ctx, false, func() error {
+			exec := s.exec
+			if s.reader != nil {
+				exec = s.reader(ctx)
+			}
+
+			start := time.Now()
+			p := s.newPendingRows(ctx, st, start)
+			var err error
+			rows, err = s.doQuery(withPendingRows(ctx, p), exec, st)
+			err = translateError(s.redactError(st, err))
+			duration := time.Since(start)
+			s.logStatement(ctx, st, duration, -1, err)
+			s.observe(st, duration, err)
+			s.trackSlowQuery(p, rows, err)
+			return err
+		})
+	})
+
+	return rows, wrapError(st, err)
+}
+
+// 执行非查询语句
+func (s *session) execute(ctx context.Context, st *statement) (sql.Result, error) {
+	s = s.bind(ctx)
+	if s.exec == nil {
+		return nil, errDBNotInit
+	}
+
+	leave, err := s.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	if timeout := s.queryTimeout(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var result sql.Result
+	var affected int64
+	err = s.withHooks(ctx, st, func(ctx context.Context) (int64, error) {
+		err := s.retry(ctx, true, func() error {
+			start := time.Now()
+			var err error
+			result, err = s.doExec(ctx, st)
+			err = translateError(s.redactError(st, err))
+			affected = 0
+			if err == nil {
+				affected, _ = result.RowsAffected()
+			}
+			duration := time.Since(start)
+			s.logStatement(ctx, st, duration, affected, err)
+			s.observe(st, duration, err)
+			s.trackSlowExec(ctx, st, duration, affected, err)
+			return err
+		})
+		return affected, err
+	})
+
+	return result, wrapError(st, err)
+}
+
+func (s *session) doQuery(ctx context.Context, exec Executor, st *statement) (*sql.Rows, error) {
+	if s.killOnCancel() {
+		if db, ok := exec.(*sql.DB); ok {
+			return killableQuery(ctx, db, st.sql, st.args...)
+		}
+		if s.connID > 0 {
+			var rows *sql.Rows
+			err := watchKill(ctx, s.inst.db, s.connID, func() (err error) {
+				rows, err = exec.QueryContext(ctx, st.sql, st.args...)
+				return err
+			})
+			return rows, err
+		}
+	}
+
+	return exec.QueryContext(ctx, st.sql, st.args...)
+}
+
+func (s *session) doExec(ctx context.Context, st *statement) (sql.Result, error) {
+	if s.killOnCancel() {
+		if db, ok := s.exec.(*sql.DB); ok {
+			return killableExec(ctx, db, st.sql, st.args...)
+		}
+		if s.connID > 0 {
+			var result sql.Result
+			err := watchKill(ctx, s.inst.db, s.connID, func() (err error) {
+				result, err = s.exec.ExecContext(ctx, st.sql, st.args...)
+				return err
+			})
+			return result, err
+		}
+	}
+
+	return s.exec.ExecContext(ctx, st.sql, st.args...)
+}
+
+// 是否为事务会话
+func (s *session) inTx() bool {
+	switch s.exec.(type) {
+	case *sql.Tx, snapshotConn:
+		return true
+	}
+	return false
+}
+
+// 不带 ctx 的方法所使用的上下文
+func (s *session) baseContext() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,156 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+)
+
+// 正在执行的操作及未结束的事务，用于优雅关闭
+type tracker struct {
+	mu      sync.Mutex
+	closing bool
+	active  int
+	txs     map[*Tx]struct{}
+	drained chan struct{}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 优雅关闭所有实例，详见 Instance.Shutdown
+// 关闭后的实例仍保留在注册表中，使后续操作返回 ErrShuttingDown 而非未初始化，可通过 Init/Register 重新注册
+func Shutdown(ctx context.Context) error {
+	dbMutex.Lock()
+	list := make([]*Instance, 0, len(instances))
+	for _, inst := range instances {
+		list = append(list, inst)
+	}
+	DB = nil
+	dbMutex.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(list))
+	for i, inst := range list {
+		wg.Add(1)
+		go func(i int, inst *Instance) {
+			defer wg.Done()
+			errs[i] = inst.Shutdown(ctx)
+		}(i, inst)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 优雅关闭：不再接受新的操作(返回 ErrShuttingDown)，已开启的事务仍可继续执行直至提交或回滚，
+// 等待正在执行的语句及事务结束，ctx 到期时回滚仍未结束的事务，最后关闭连接池
+func (i *Instance) Shutdown(ctx context.Context) error {
+	i.tracker.mu.Lock()
+	i.tracker.closing = true
+	if i.tracker.drained == nil {
+		i.tracker.drained = make(chan struct{})
+	}
+	drained := i.tracker.drained
+	i.tracker.checkDrained()
+	i.tracker.mu.Unlock()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		i.tracker.mu.Lock()
+		txs := make([]*Tx, 0, len(i.tracker.txs))
+		for tx := range i.tracker.txs {
+			txs = append(txs, tx)
+		}
+		i.tracker.mu.Unlock()
+
+		// 事务可能仍在其他 goroutine 中使用，直接回滚底层事务，不修改 Tx 的状态
+		for _, tx := range txs {
+			_ = tx.finish(false)
+			i.tracker.removeTx(tx)
+		}
+	}
+
+	if closeErr := i.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 开始一个操作，关闭中时返回 ErrShuttingDown；inTx 为 true 时表示已开启事务中的操作，允许继续执行
+func (t *tracker) enter(inTx bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closing && !inTx {
+		return ErrShuttingDown
+	}
+	t.active++
+	return nil
+}
+
+// 结束一个操作
+func (t *tracker) leave() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+	t.checkDrained()
+}
+
+// 记录开启的事务
+func (t *tracker) addTx(tx *Tx) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closing {
+		return ErrShuttingDown
+	}
+	if t.txs == nil {
+		t.txs = make(map[*Tx]struct{})
+	}
+	t.txs[tx] = struct{}{}
+	return nil
+}
+
+// 移除已结束的事务
+func (t *tracker) removeTx(tx *Tx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.txs, tx)
+	t.checkDrained()
+}
+
+// 关闭中且没有正在执行的操作和事务时通知 Shutdown，需持有锁
+func (t *tracker) checkDrained() {
+	if !t.closing || t.active > 0 || len(t.txs) > 0 {
+		return
+	}
+	select {
+	case <-t.drained:
+	default:
+		close(t.drained)
+	}
+}
+
+// 开始会话中的一个操作，返回结束函数
+func (s *session) enter() (func(), error) {
+	if s.inst == nil {
+		return func() {}, nil
+	}
+
+	if err := s.inst.tracker.enter(s.inTx()); err != nil {
+		return nil, err
+	}
+	return s.inst.tracker.leave, nil
+}
@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForTx(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- inst.Shutdown(context.Background()) }()
+	waitClosing(t, inst)
+
+	// 新的操作被拒绝，已开启的事务可继续执行
+	if _, err := inst.Insert("user", map[string]interface{}{"Name": "sam"}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("insert err = %v, want ErrShuttingDown", err)
+	}
+	if _, err := inst.Begin(); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("begin err = %v, want ErrShuttingDown", err)
+	}
+	if _, err := tx.Insert("user", map[string]interface{}{"Name": "sam"}); err != nil {
+		t.Errorf("insert in tx err = %v", err)
+	}
+	nested, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nested.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned %v before the tx ended", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	assertTxs(t, rec, []string{"BEGIN", "COMMIT"})
+	if n := len(rec.Statements()); n != 3 {
+		t.Errorf("got %d statements, want 3", n)
+	}
+}
+
+func TestShutdownDrainsStatements(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	started, release := make(chan struct{}), make(chan struct{})
+	rec.failWith(func(string) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	counted := make(chan error, 1)
+	go func() {
+		_, err := inst.Count("user", nil)
+		counted <- err
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- inst.Shutdown(context.Background()) }()
+	waitClosing(t, inst)
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned %v before the statement ended", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-counted; err != nil {
+		t.Errorf("count err = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownRollsBackAtDeadline(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := inst.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown err = %v, want context.DeadlineExceeded", err)
+	}
+	assertTxs(t, rec, []string{"BEGIN", "ROLLBACK"})
+
+	if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("commit after forced rollback err = %v, want sql.ErrTxDone", err)
+	}
+	assertTxs(t, rec, []string{"BEGIN", "ROLLBACK"})
+}
+
+func TestPackageShutdownKeepsInstances(t *testing.T) {
+	inst, _ := newFakeInstance(t, &Options{DisableStatementLog: true})
+	setDefault(t, inst)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if Default() != inst {
+		t.Fatal("default instance should stay registered")
+	}
+	if GetDB() != nil {
+		t.Error("GetDB should return nil after shutdown")
+	}
+	if _, err := Insert("user", map[string]interface{}{"Name": "sam"}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("insert err = %v, want ErrShuttingDown", err)
+	}
+}
+
+// 等待实例进入关闭状态
+func waitClosing(t *testing.T, inst *Instance) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		inst.tracker.mu.Lock()
+		closing := inst.tracker.closing
+		inst.tracker.mu.Unlock()
+		if closing {
+			return
+		}
+	}
+	t.Fatal("instance is not shutting down")
+}
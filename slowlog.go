@@ -0,0 +1,197 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 慢查询
+type SlowQuery struct {
+	Instance    string
+	Operation   string // select、insert、update、delete、batch_insert、exec
+	Table       string
+	Statement   string
+	Fingerprint string        // 语句指纹，见 Fingerprint
+	Duration    time.Duration // 查询语句为执行及读取结果的总耗时
+	Rows        int64         // 返回或影响的行数，未知时为 -1
+	Caller      string        // 调用方 file:line
+	Err         error
+}
+
+// 查询结果，由驱动返回后关联至 *sql.Rows，供 Load 获取语句；关闭时取消关联，并按执行及读取结果的总耗时判断慢查询
+type pendingRows struct {
+	sess   *session
+	ctx    context.Context
+	st     *statement
+	begin  time.Time
+	caller string
+
+	mu      sync.Mutex
+	rows    *sql.Rows
+	claimed bool // 驱动已返回结果，关闭时会调用 finish
+	closed  bool
+}
+
+const (
+	callerDepth = 32
+)
+
+var (
+	pkgPath    = reflect.TypeOf(Query{}).PkgPath()
+	pendingMap sync.Map // *sql.Rows -> *pendingRows
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 是否开启慢查询记录
+func (s *session) slowThreshold() time.Duration {
+	if s.inst == nil {
+		return 0
+	}
+	return s.inst.opts.SlowThreshold
+}
+
+// 查询语句开始执行前调用，返回的 pendingRows 需通过 withPendingRows 传递给驱动
+func (s *session) newPendingRows(ctx context.Context, st *statement, begin time.Time) *pendingRows {
+	p := &pendingRows{sess: s, ctx: ctx, st: st, begin: begin}
+	if s.slowThreshold() > 0 {
+		p.caller = callerOutsidePackage()
+	}
+	return p
+}
+
+// 查询语句执行后调用：结果关闭后再判断慢查询；执行失败或驱动未包装时立即按查询耗时判断
+func (s *session) trackSlowQuery(p *pendingRows, rows *sql.Rows, err error) {
+	if err == nil && rows != nil && p.attach(rows) {
+		return
+	}
+	if s.slowThreshold() > 0 {
+		s.reportSlow(p.ctx, p.st, time.Since(p.begin), -1, p.caller, err)
+	}
+}
+
+// 非查询语句
+func (s *session) trackSlowExec(ctx context.Context, st *statement, duration time.Duration, affected int64, err error) {
+	if s.slowThreshold() <= 0 {
+		return
+	}
+	if err != nil {
+		affected = -1
+	}
+	s.reportSlow(ctx, st, duration, affected, callerOutsidePackage(), err)
+}
+
+// 查询结果对应的语句，未知或结果已关闭时返回 nil
+func rowsStatement(rows *sql.Rows) *statement {
+	if value, ok := pendingMap.Load(rows); ok {
+		return value.(*pendingRows).st
+	}
+	return nil
+}
+
+// 驱动返回结果时调用，同一查询只关联一次
+func (p *pendingRows) claim() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.claimed {
+		return false
+	}
+	p.claimed = true
+	return true
+}
+
+// 关联 *sql.Rows，驱动未返回结果时返回 false；结果在关联前已关闭时不再记录
+func (p *pendingRows) attach(rows *sql.Rows) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.claimed {
+		return false
+	}
+	if !p.closed {
+		p.rows = rows
+		pendingMap.Store(rows, p)
+	}
+	return true
+}
+
+// 结果关闭时由驱动调用，count 为读取的行数
+func (p *pendingRows) finish(count int64, err error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	if p.rows != nil {
+		pendingMap.Delete(p.rows)
+	}
+	p.mu.Unlock()
+
+	if p.sess.slowThreshold() > 0 {
+		p.sess.reportSlow(p.ctx, p.st, time.Since(p.begin), count, p.caller, err)
+	}
+}
+
+// 超过阈值时回调 Options.OnSlowQuery，未指定时以 LevelWarn 记录日志
+func (s *session) reportSlow(ctx context.Context, st *statement, duration time.Duration, rows int64, caller string, err error) {
+	if duration < s.slowThreshold() {
+		return
+	}
+
+	slow := SlowQuery{
+		Instance:    s.inst.name,
+		Operation:   st.op,
+		Table:       st.table,
+		Statement:   s.redactSQL(st, st.sql),
+		Fingerprint: Fingerprint(st.sql),
+		Duration:    duration,
+		Rows:        rows,
+		Caller:      caller,
+		Err:         err,
+	}
+
+	if s.inst.opts.OnSlowQuery != nil {
+		s.inst.opts.OnSlowQuery(ctx, slow)
+		return
+	}
+
+	fields := []Field{
+		{Key: "instance", Value: slow.Instance},
+		{Key: "operation", Value: slow.Operation},
+		{Key: "table", Value: slow.Table},
+		{Key: "fingerprint", Value: slow.Fingerprint},
+		{Key: "duration", Value: slow.Duration},
+		{Key: "rows", Value: slow.Rows},
+		{Key: "caller", Value: slow.Caller},
+	}
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err})
+	}
+	if s.inst.opts.LogLevel <= LevelWarn {
+		s.logger().Log(ctx, LevelWarn, "slow statement", fields...)
+	}
+}
+
+// 获取包外的调用方 file:line
+func callerOutsidePackage() string {
+	pcs := make([]uintptr, callerDepth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPath+".") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
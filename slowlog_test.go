@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// 记录慢查询回调
+type slowRecorder struct {
+	mu    sync.Mutex
+	slows []SlowQuery
+}
+
+func (r *slowRecorder) record(_ context.Context, slow SlowQuery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.slows = append(r.slows, slow)
+}
+
+func (r *slowRecorder) Slows() []SlowQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SlowQuery(nil), r.slows...)
+}
+
+// 统计 pendingMap 中的查询结果
+func pendingCount() int {
+	n := 0
+	pendingMap.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestSlowQueryOnRowsClose(t *testing.T) {
+	cases := []struct {
+		name string
+		read func(t *testing.T, inst *Instance)
+		rows int64
+	}{
+		{
+			name: "load",
+			read: func(t *testing.T, inst *Instance) {
+				rows, err := inst.SelectBySql("SELECT 1 FROM `user` WHERE ID = ?", 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var n int
+				if err := LoadValue(rows, &n); err != nil {
+					t.Fatal(err)
+				}
+			},
+			rows: 1,
+		},
+		{
+			name: "next",
+			read: func(t *testing.T, inst *Instance) {
+				rows, err := inst.SelectBySql("SELECT 1 FROM `user` WHERE ID = ?", 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := pendingCount(); n != 1 {
+					t.Errorf("got %d pending rows before close, want 1", n)
+				}
+				for rows.Next() {
+				}
+				if err := rows.Close(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			rows: 1,
+		},
+		{
+			name: "closed without reading",
+			read: func(t *testing.T, inst *Instance) {
+				rows, err := inst.SelectBySql("SELECT 1 FROM `user` WHERE ID = ?", 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := rows.Close(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			rows: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := &slowRecorder{}
+			inst, _ := newFakeInstance(t, &Options{SlowThreshold: 1, OnSlowQuery: rec.record, DisableStatementLog: true})
+			c.read(t, inst)
+
+			slows := rec.Slows()
+			if len(slows) != 1 {
+				t.Fatalf("got %d slow queries, want 1", len(slows))
+			}
+			if slows[0].Rows != c.rows || slows[0].Operation != opSelect || slows[0].Caller == "" {
+				t.Errorf("got slow query %+v", slows[0])
+			}
+			if n := pendingCount(); n != 0 {
+				t.Errorf("got %d pending rows after close, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	killQueryTimeout = 5 * time.Second // 执行 KILL QUERY 的超时
+)
+
+type timeoutCtxKey struct{}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 为ctx中的操作指定语句超时，覆盖实例的 Options.QueryTimeout，d<=0 表示不限制
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutCtxKey{}, d)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 获取语句超时：ctx 中指定的优先，其次为实例默认值
+func (s *session) queryTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(timeoutCtxKey{}).(time.Duration); ok {
+		return d
+	}
+	if s.inst != nil {
+		return s.inst.opts.QueryTimeout
+	}
+	return 0
+}
+
+// 是否在ctx取消时 KILL QUERY
+func (s *session) killOnCancel() bool {
+	return s.inst != nil && s.inst.opts.KillOnCancel
+}
+
+// 在 SELECT 语句中注入 MAX_EXECUTION_TIME 提示(毫秒)
+func addMaxExecutionTime(cmd string, d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms <= 0 || len(cmd) < 6 || !strings.EqualFold(cmd[:6], "SELECT") {
+		return cmd
+	}
+	return fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */%s", ms, cmd[6:])
+}
+
+// 获取连接ID，exec 为 *sql.Conn 或 *sql.Tx
+func connectionID(ctx context.Context, exec queryer) (int64, error) {
+	var id int64
+	// 不关联语句的 pendingRows
+	rows, err := exec.QueryContext(withPendingRows(ctx, nil), "SELECT CONNECTION_ID()")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := Load(rows, &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// 在fn执行期间监听ctx，ctx取消时通过db的另一个连接执行 KILL QUERY，使服务端同时停止执行
+// 监听在fn返回后即结束，避免连接归还连接池后误杀其他语句；因此查询语句仅覆盖返回 rows 之前的执行阶段，
+// 读取结果期间 ctx 取消时由驱动关闭连接，连接不会归还连接池，服务端在发送数据失败后停止执行
+func watchKill(ctx context.Context, db *sql.DB, connID int64, fn func() error) error {
+	done := make(chan struct{})
+	killed := make(chan struct{})
+	go func() {
+		defer close(killed)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			killCtx, cancel := context.WithTimeout(context.Background(), killQueryTimeout)
+			defer cancel()
+			_, _ = db.ExecContext(killCtx, fmt.Sprintf("KILL QUERY %d", connID))
+		}
+	}()
+
+	err := fn()
+	close(done)
+	<-killed
+
+	return err
+}
+
+// 在独立连接上执行查询，ctx 取消时 KILL QUERY
+func killableQuery(ctx context.Context, db *sql.DB, cmd string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	connID, err := connectionID(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	var rows *sql.Rows
+	err = watchKill(ctx, db, connID, func() error {
+		rows, err = conn.QueryContext(ctx, cmd, args...)
+		return err
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// Close 会阻塞至 rows 关闭后才将连接归还连接池
+	go func() { _ = conn.Close() }()
+
+	return rows, nil
+}
+
+// 在独立连接上执行语句，ctx 取消时 KILL QUERY
+func killableExec(ctx context.Context, db *sql.DB, cmd string, args ...interface{}) (sql.Result, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	connID, err := connectionID(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var result sql.Result
+	err = watchKill(ctx, db, connID, func() error {
+		result, err = conn.ExecContext(ctx, cmd, args...)
+		return err
+	})
+	return result, err
+}
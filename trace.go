@@ -0,0 +1,89 @@
+package mysql
+
+import "context"
+
+// 链路追踪接口，与 OpenTelemetry 的 trace.Tracer 对应，父 span 由 Tracer 从 ctx 中获取
+// 通过 Options.Tracer 配置后，每条语句、每个事务及 BatchInsert 均会生成 span
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span)
+}
+
+// span 接口，与 OpenTelemetry 的 trace.Span 对应
+type Span interface {
+	SetAttributes(attrs ...Field)
+	RecordError(err error)
+	End()
+}
+
+// span 属性名，与 OpenTelemetry 数据库语义约定一致
+const (
+	AttrDBSystem     = "db.system"
+	AttrDBStatement  = "db.statement"
+	AttrDBOperation  = "db.operation"
+	AttrDBTable      = "db.sql.table"
+	AttrRowsAffected = "db.rows_affected"
+	AttrBatchSize    = "db.operation.batch.size"
+)
+
+// 语句 span 钩子，位于所有钩子之前
+type tracingHook struct {
+	tracer Tracer
+}
+
+type spanCtxKey struct{}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (h tracingHook) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	attrs := []Field{{AttrDBSystem, "mysql"}, {AttrDBOperation, event.Operation}}
+	if event.Table != "" {
+		attrs = append(attrs, Field{AttrDBTable, event.Table})
+	}
+
+	ctx, span := h.tracer.Start(ctx, spanName(event.Operation, event.Table), attrs...)
+	return context.WithValue(ctx, spanCtxKey{}, span), nil
+}
+
+func (h tracingHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	span, ok := ctx.Value(spanCtxKey{}).(Span)
+	if !ok {
+		return
+	}
+
+	// 语句可能已被后续钩子改写，在结束时记录最终执行的语句，字面量替换为 ?
+	span.SetAttributes(Field{AttrDBStatement, Fingerprint(event.Statement)})
+	if event.RowsAffected >= 0 {
+		span.SetAttributes(Field{AttrRowsAffected, event.RowsAffected})
+	}
+	endSpan(span, event.Err)
+}
+
+func (s *session) tracer() Tracer {
+	if s.inst == nil {
+		return nil
+	}
+	return s.inst.opts.Tracer
+}
+
+// 结束事务 span，提交后的 Rollback 不再重复记录
+func (t *Tx) endSpan(err error) {
+	if t.span == nil {
+		return
+	}
+	endSpan(t.span, err)
+	t.span = nil
+}
+
+func spanName(op, table string) string {
+	if table == "" {
+		return op
+	}
+	return op + " " + table
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
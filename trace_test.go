@@ -0,0 +1,214 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 内存 Tracer，记录已结束的 span
+type memoryTracer struct {
+	mu    sync.Mutex
+	spans []spanData
+	seq   uint64
+}
+
+// 已结束的 span
+type spanData struct {
+	name       string
+	spanID     uint64
+	parentID   uint64 // 根 span 为 0
+	attributes []Field
+	err        error
+}
+
+type memorySpan struct {
+	tracer *memoryTracer
+	data   spanData
+	ended  bool
+}
+
+type memorySpanKey struct{}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func (m *memoryTracer) Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	span := &memorySpan{tracer: m, data: spanData{
+		name:       name,
+		spanID:     atomic.AddUint64(&m.seq, 1),
+		attributes: append([]Field(nil), attrs...),
+	}}
+	if parent, ok := ctx.Value(memorySpanKey{}).(*memorySpan); ok {
+		span.data.parentID = parent.data.spanID
+	}
+	return context.WithValue(ctx, memorySpanKey{}, span), span
+}
+
+// 按结束顺序返回已结束的 span
+func (m *memoryTracer) Spans() []spanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]spanData(nil), m.spans...)
+}
+
+func (s *memorySpan) SetAttributes(attrs ...Field) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.data.attributes = append(s.data.attributes, attrs...)
+}
+
+func (s *memorySpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.data.err = err
+}
+
+func (s *memorySpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.tracer.spans = append(s.tracer.spans, s.data)
+}
+
+// 获取属性值，不存在时返回 nil
+func (d spanData) attr(key string) interface{} {
+	for i := len(d.attributes) - 1; i >= 0; i-- {
+		if d.attributes[i].Key == key {
+			return d.attributes[i].Value
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+func TestTracingStatement(t *testing.T) {
+	tracer := &memoryTracer{}
+	inst, rec := newFakeInstance(t, &Options{Tracer: tracer, DisableStatementLog: true})
+
+	if _, err := inst.Update("user", map[string]interface{}{"Name": "sam"}, map[string]interface{}{"ID = ?": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.name != "update user" || span.parentID != 0 || span.err != nil {
+		t.Errorf("got span %q parent=%d err=%v", span.name, span.parentID, span.err)
+	}
+
+	statements := rec.Statements()
+	if len(statements) != 1 {
+		t.Fatalf("got %d statements, want 1", len(statements))
+	}
+	want := map[string]interface{}{
+		AttrDBSystem:     "mysql",
+		AttrDBOperation:  opUpdate,
+		AttrDBTable:      "user",
+		AttrDBStatement:  Fingerprint(statements[0].query),
+		AttrRowsAffected: int64(1),
+	}
+	for key, value := range want {
+		if got := span.attr(key); got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+}
+
+func TestTracingTransaction(t *testing.T) {
+	tracer := &memoryTracer{}
+	inst, _ := newFakeInstance(t, &Options{Tracer: tracer, DisableStatementLog: true})
+
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Insert("user", map[string]interface{}{"Name": "sam"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.CountContext(tx.Context(), "user", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// 提交后的 Rollback 不产生 span
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.Spans()
+	names := []string{"begin", "insert user", "select user", "commit", "transaction"}
+	if len(spans) != len(names) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(names))
+	}
+
+	root := spans[len(spans)-1]
+	if root.parentID != 0 || root.attr(AttrDBSystem) != "mysql" {
+		t.Errorf("transaction span parent=%d system=%v", root.parentID, root.attr(AttrDBSystem))
+	}
+	for n, span := range spans {
+		if span.name != names[n] {
+			t.Errorf("span %d is %q, want %q", n, span.name, names[n])
+		}
+		if span.spanID != root.spanID && span.parentID != root.spanID {
+			t.Errorf("span %q parent=%d, want %d", span.name, span.parentID, root.spanID)
+		}
+		if span.spanID != root.spanID && span.attr(AttrDBOperation) == nil {
+			t.Errorf("span %q has no %s", span.name, AttrDBOperation)
+		}
+	}
+}
+
+func TestTracingBatchInsert(t *testing.T) {
+	tracer := &memoryTracer{}
+	inst, rec := newFakeInstance(t, &Options{Tracer: tracer, DisableStatementLog: true})
+
+	rows := maxBatchLimit + 1
+	params := make([]interface{}, 0, rows)
+	for i := 0; i < rows; i++ {
+		params = append(params, []interface{}{fmt.Sprintf("user%d", i), i})
+	}
+	if _, _, err := inst.BatchInsert("user", []string{"Name", "Age"}, params); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(rec.Statements()); n != 2 {
+		t.Fatalf("got %d statements, want 2", n)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	parent := spans[2]
+	if parent.name != "batch_insert user" || parent.parentID != 0 {
+		t.Errorf("got parent span %q parent=%d", parent.name, parent.parentID)
+	}
+	if got := parent.attr(AttrBatchSize); got != rows {
+		t.Errorf("%s = %v, want %d", AttrBatchSize, got, rows)
+	}
+	if got := parent.attr(AttrDBTable); got != "user" {
+		t.Errorf("%s = %v, want user", AttrDBTable, got)
+	}
+
+	for _, chunk := range spans[:2] {
+		if chunk.parentID != parent.spanID {
+			t.Errorf("chunk span parent=%d, want %d", chunk.parentID, parent.spanID)
+		}
+		if chunk.attr(AttrDBOperation) != opBatchInsert || chunk.attr(AttrDBStatement) == nil {
+			t.Errorf("chunk span operation=%v statement=%v", chunk.attr(AttrDBOperation), chunk.attr(AttrDBStatement))
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 事务：提供与包级函数相同的操作，所有操作均在同一个连接上执行
+// 在已开启的事务中再次开启事务时，使用 SAVEPOINT 实现嵌套
+type Tx struct {
+	session
+	tx        *sql.Tx
+	conn      *sql.Conn // 一致性快照事务所在的连接，此时 tx 为空
+	db        *sql.DB   // 开启事务的连接池
+	savepoint string    // 嵌套事务的保存点名称，最外层事务为空
+	seq       *int      // 保存点序号，同一个最外层事务内共享
+	done      bool      // 事务是否已结束
+	committed bool      // 事务是否已成功提交
+	span      Span      // 最外层事务的 span，未配置 Tracer 时为空
+}
+
+// 事务选项，嵌套事务(保存点)忽略 Isolation、ReadOnly 和 ConsistentSnapshot
+type TxOptions struct {
+	Isolation          sql.IsolationLevel              // 隔离级别，默认使用会话的隔离级别
+	ReadOnly           bool                            // 只读事务：START TRANSACTION READ ONLY
+	ConsistentSnapshot bool                            // 一致性快照：在独占连接上执行 START TRANSACTION WITH CONSISTENT SNAPSHOT，仅 REPEATABLE READ 下有效，ctx 取消时不会自动回滚
+	MaxAttempts        int                             // WithTx 最大执行次数，遇到死锁(1213)或锁等待超时(1205)时重试，<=1 表示不重试
+	Backoff            func(attempt int) time.Duration // 第 attempt 次失败后的等待时长，为空时使用 defaultTxBackoff
+}
+
+type txCtxKey struct{}
+
+// 一致性快照事务的执行器：事务由手动执行的 START TRANSACTION 开启，语句均在同一连接上执行
+type snapshotConn struct {
+	conn *sql.Conn
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 开启事务
+func Begin() (*Tx, error) {
+	return Default().Begin()
+}
+
+// 基于上下文和选项开启事务，若ctx中已携带事务(见 Tx.Context)，则开启基于保存点的嵌套事务
+func BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	return Default().BeginTx(ctx, opts)
+}
+
+// 获取ctx中携带的事务，不存在时返回nil
+func TxFromContext(ctx context.Context) *Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txCtxKey{}).(*Tx)
+	return tx
+}
+
+// 在事务中执行fn，详见 Instance.WithTx
+func WithTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+	return Default().WithTx(ctx, opts, fn)
+}
+
+// 在当前事务中开启嵌套事务：SAVEPOINT sp_N
+func (t *Tx) Begin() (*Tx, error) {
+	*t.seq++
+	savepoint := fmt.Sprintf("sp_%d", *t.seq)
+	if _, err := t.execute(t.ctx, &statement{op: opExec, sql: fmt.Sprintf("SAVEPOINT %s", savepoint)}); err != nil {
+		return nil, err
+	}
+
+	nested := &Tx{session: t.session, tx: t.tx, conn: t.conn, db: t.db, savepoint: savepoint, seq: t.seq}
+	nested.ctx = context.WithValue(t.ctx, txCtxKey{}, nested)
+
+	return nested, nil
+}
+
+// 提交事务，嵌套事务则释放保存点：RELEASE SAVEPOINT sp_N
+func (t *Tx) Commit() error {
+	if t.savepoint == "" {
+		if t.done {
+			return sql.ErrTxDone
+		}
+		t.done = true
+		defer t.inst.tracker.removeTx(t)
+		err := t.withHooks(t.ctx, &statement{op: opCommit, sql: "COMMIT"}, func(context.Context) (int64, error) {
+			return -1, translateError(t.finish(true))
+		})
+		t.endSpan(err)
+		t.committed = err == nil
+		return err
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.execute(t.ctx, &statement{op: opExec, sql: fmt.Sprintf("RELEASE SAVEPOINT %s", t.savepoint)})
+	t.committed = err == nil
+	return err
+}
+
+// 回滚事务，嵌套事务则回滚至保存点：ROLLBACK TO SAVEPOINT sp_N，事务已结束时忽略
+func (t *Tx) Rollback() error {
+	if t.savepoint == "" {
+		if t.done {
+			return nil
+		}
+		t.done = true
+		defer t.inst.tracker.removeTx(t)
+		err := t.withHooks(t.ctx, &statement{op: opRollback, sql: "ROLLBACK"}, func(context.Context) (int64, error) {
+			if err := t.finish(false); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				return -1, err
+			}
+			return -1, nil
+		})
+		t.endSpan(err)
+		return err
+	}
+
+	if t.done {
+		return nil
+	}
+	t.done = true
+	_, err := t.execute(t.ctx, &statement{op: opExec, sql: fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", t.savepoint)})
+	return err
+}
+
+// 获取携带当前事务的上下文，将其传给 BeginTx/WithTx 即可开启嵌套事务
+func (t *Tx) Context() context.Context {
+	return t.ctx
+}
+
+// 是否为嵌套事务
+func (t *Tx) IsNested() bool {
+	return t.savepoint != ""
+}
+
+// 获取原生事务，一致性快照事务返回 nil
+func (t *Tx) GetTx() *sql.Tx {
+	return t.tx
+}
+
+// 万能加载，同包级 Load
+func (t *Tx) Load(rows *sql.Rows, value interface{}) (int, error) {
+	return Load(rows, value)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// 结束最外层事务，一致性快照事务执行 COMMIT/ROLLBACK 后将连接归还连接池，失败时丢弃该连接
+func (t *Tx) finish(commit bool) error {
+	if t.conn == nil {
+		if commit {
+			return t.tx.Commit()
+		}
+		return t.tx.Rollback()
+	}
+
+	cmd := "ROLLBACK"
+	if commit {
+		cmd = "COMMIT"
+	}
+	_, err := t.conn.ExecContext(context.Background(), cmd)
+	if errors.Is(err, sql.ErrConnDone) {
+		return sql.ErrTxDone
+	}
+	if err != nil {
+		_ = t.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	if closeErr := t.conn.Close(); err == nil && !errors.Is(closeErr, sql.ErrConnDone) {
+		err = closeErr
+	}
+	return err
+}
+
+// 在独占连接上开启一致性快照事务：SET TRANSACTION 只作用于下一个事务，因此须在 START TRANSACTION 之前执行
+func (i *Instance) beginSnapshot(ctx context.Context, opts *TxOptions) (*sql.Conn, error) {
+	var cmds []string
+	if opts.Isolation != sql.LevelDefault {
+		level, err := isolationLevelSQL(opts.Isolation)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, fmt.Sprintf("SET TRANSACTION ISOLATION LEVEL %s", level))
+	}
+	cmd := "START TRANSACTION WITH CONSISTENT SNAPSHOT"
+	if opts.ReadOnly {
+		cmd += ", READ ONLY"
+	}
+	cmds = append(cmds, cmd)
+
+	conn, err := i.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		if _, err := conn.ExecContext(ctx, cmd); err != nil {
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c snapshotConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(context.Background(), query, args...)
+}
+
+func (c snapshotConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (c snapshotConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(ctx, query, args...)
+}
+
+func (c snapshotConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(ctx, query, args...)
+}
+
+func isolationLevelSQL(level sql.IsolationLevel) (string, error) {
+	switch level {
+	case sql.LevelReadUncommitted:
+		return "READ UNCOMMITTED", nil
+	case sql.LevelReadCommitted:
+		return "READ COMMITTED", nil
+	case sql.LevelRepeatableRead:
+		return "REPEATABLE READ", nil
+	case sql.LevelSerializable:
+		return "SERIALIZABLE", nil
+	}
+	return "", fmt.Errorf("mysql: unsupported isolation level: %v", level)
+}
+
+// 是否可重试：死锁或锁等待超时
+func isTxRetryable(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == errNumDeadlock || myErr.Number == errNumLockWaitTimeout
+	}
+	return false
+}
+
+// 默认重试等待：20ms、40ms、80ms...，最长1s
+func defaultTxBackoff(attempt int) time.Duration {
+	d := 20 * time.Millisecond << uint(attempt-1)
+	if d <= 0 || d > time.Second {
+		d = time.Second
+	}
+	return d
+}
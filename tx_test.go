@@ -0,0 +1,397 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestContextTx(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	other, otherRec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	setDefault(t, inst)
+
+	// 占用另一个连接，事务外的语句不会恰好使用事务的连接
+	busy, err := inst.GetDB().Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"Name": "sam"}
+	if _, err := inst.InsertContext(tx.Context(), "user", data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InsertContext(tx.Context(), "user", data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inst.CountContext(tx.Context(), "user", nil); err != nil {
+		t.Fatal(err)
+	}
+	// 其他实例不使用该事务
+	if _, err := other.InsertContext(tx.Context(), "user", data); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// 事务结束后的 ctx 不再可用
+	if _, err := inst.InsertContext(tx.Context(), "user", data); err == nil {
+		t.Error("insert with a committed tx should fail")
+	}
+
+	txs := rec.Txs()
+	if len(txs) != 2 || txs[0].query != "BEGIN" || txs[1].query != "COMMIT" {
+		t.Fatalf("got tx statements %+v", txs)
+	}
+	statements := rec.Statements()
+	if len(statements) != 3 {
+		t.Fatalf("got %d statements, want 3", len(statements))
+	}
+	for _, st := range statements {
+		if st.conn != txs[0].conn {
+			t.Errorf("%q ran on conn %d, want tx conn %d", st.query, st.conn, txs[0].conn)
+		}
+	}
+	if n := len(otherRec.Statements()); n != 1 || len(otherRec.Txs()) != 0 {
+		t.Errorf("other instance got %d statements and %d tx statements", n, len(otherRec.Txs()))
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	insert := func(tx *Tx) error {
+		_, err := tx.Insert("user", map[string]interface{}{"Name": "sam"})
+		return err
+	}
+	boom := errors.New("boom")
+
+	cases := []struct {
+		name string
+		opts *TxOptions
+		fail func(n int) error // 第 n 条语句的执行结果
+		fn   func(tx *Tx) error
+		err  error
+		txs  []string
+	}{
+		{name: "commit", fn: insert, txs: []string{"BEGIN", "COMMIT"}},
+		{
+			name: "rollback on error",
+			fn: func(tx *Tx) error {
+				if err := insert(tx); err != nil {
+					return err
+				}
+				return boom
+			},
+			err: boom,
+			txs: []string{"BEGIN", "ROLLBACK"},
+		},
+		{
+			name: "committed by fn",
+			fn: func(tx *Tx) error {
+				if err := insert(tx); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			txs: []string{"BEGIN", "COMMIT"},
+		},
+		{
+			name: "rolled back by fn",
+			fn: func(tx *Tx) error {
+				return tx.Rollback()
+			},
+			err: sql.ErrTxDone,
+			txs: []string{"BEGIN", "ROLLBACK"},
+		},
+		{
+			name: "retry on deadlock",
+			opts: &TxOptions{MaxAttempts: 3, Backoff: func(int) time.Duration { return 0 }},
+			fail: func(n int) error {
+				if n == 1 {
+					return &mysqldriver.MySQLError{Number: errNumDeadlock, Message: "Deadlock found"}
+				}
+				return nil
+			},
+			fn:  insert,
+			txs: []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"},
+		},
+		{
+			name: "retry on lock wait timeout until max attempts",
+			opts: &TxOptions{MaxAttempts: 2, Backoff: func(int) time.Duration { return 0 }},
+			fail: func(int) error {
+				return &mysqldriver.MySQLError{Number: errNumLockWaitTimeout, Message: "Lock wait timeout exceeded"}
+			},
+			fn:  insert,
+			err: ErrLockWaitTimeout,
+			txs: []string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"},
+		},
+		{
+			name: "no retry on other errors",
+			opts: &TxOptions{MaxAttempts: 3, Backoff: func(int) time.Duration { return 0 }},
+			fail: func(int) error {
+				return &mysqldriver.MySQLError{Number: errNumDuplicateKey, Message: "Duplicate entry 'sam' for key 'user.idx_name'"}
+			},
+			fn:  insert,
+			err: ErrDuplicateKey,
+			txs: []string{"BEGIN", "ROLLBACK"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			if c.fail != nil {
+				n := 0
+				rec.failWith(func(string) error {
+					n++
+					return c.fail(n)
+				})
+			}
+
+			if err := inst.WithTx(context.Background(), c.opts, c.fn); !errors.Is(err, c.err) {
+				t.Fatalf("err = %v, want %v", err, c.err)
+			}
+			assertTxs(t, rec, c.txs)
+		})
+	}
+
+	t.Run("rollback then re-panic", func(t *testing.T) {
+		inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want boom", r)
+			}
+			assertTxs(t, rec, []string{"BEGIN", "ROLLBACK"})
+		}()
+
+		_ = inst.WithTx(context.Background(), nil, func(tx *Tx) error {
+			if err := insert(tx); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+		t.Error("WithTx should re-panic")
+	})
+}
+
+// 校验事务的开始、提交及回滚顺序
+func assertTxs(t *testing.T, rec *fakeRecorder, want []string) {
+	t.Helper()
+
+	var got []string
+	for _, st := range rec.Txs() {
+		got = append(got, st.query)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got txs %q, want %q", got, want)
+	}
+}
+
+func TestTx(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+		tx, err := inst.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Insert("user", map[string]interface{}{"Name": "sam"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Update("user", map[string]interface{}{"Age": 18}, map[string]interface{}{"Name = ?": "sam"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Count("user", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("second commit err = %v, want sql.ErrTxDone", err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("rollback after commit err = %v", err)
+		}
+
+		assertTxs(t, rec, []string{"BEGIN", "COMMIT"})
+		assertSameConn(t, rec, 3)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+		tx, err := inst.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Delete("user", map[string]interface{}{"ID = ?": 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("second rollback err = %v", err)
+		}
+		if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("commit after rollback err = %v, want sql.ErrTxDone", err)
+		}
+		if _, err := tx.Delete("user", map[string]interface{}{"ID = ?": 1}); !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("statement after rollback err = %v, want sql.ErrTxDone", err)
+		}
+
+		assertTxs(t, rec, []string{"BEGIN", "ROLLBACK"})
+		assertSameConn(t, rec, 1)
+	})
+}
+
+func TestSavepoint(t *testing.T) {
+	inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+	tx, err := inst.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.IsNested() {
+		t.Error("root tx should not be nested")
+	}
+
+	outer, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := inst.BeginTx(outer.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outer.IsNested() || !inner.IsNested() {
+		t.Error("savepoint tx should be nested")
+	}
+	if err := inner.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("commit after rollback err = %v, want sql.ErrTxDone", err)
+	}
+	if err := outer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := outer.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("second commit err = %v, want sql.ErrTxDone", err)
+	}
+
+	// 保存点序号在最外层事务内递增
+	boom := errors.New("boom")
+	if err := inst.WithTx(tx.Context(), nil, func(*Tx) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, st := range rec.Statements() {
+		got = append(got, st.query)
+	}
+	want := []string{
+		"SAVEPOINT sp_1",
+		"SAVEPOINT sp_2",
+		"ROLLBACK TO SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_3",
+		"ROLLBACK TO SAVEPOINT sp_3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got statements %q, want %q", got, want)
+	}
+	assertTxs(t, rec, []string{"BEGIN", "COMMIT"})
+	assertSameConn(t, rec, len(want))
+}
+
+func TestSnapshotTx(t *testing.T) {
+	opts := &TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true, ConsistentSnapshot: true}
+	begin := []string{
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	}
+	count := "SELECT COUNT(0) FROM `user`"
+
+	cases := []struct {
+		name   string
+		fail   string // 执行失败的语句
+		commit bool
+		err    bool
+		want   []string
+		open   int // 结束后连接池中的连接数，失败的连接被丢弃
+	}{
+		{name: "commit", commit: true, want: append(begin, count, "COMMIT"), open: 1},
+		{name: "rollback", want: append(begin, count, "ROLLBACK"), open: 1},
+		{name: "commit failed", fail: "COMMIT", commit: true, err: true, want: append(begin, count, "COMMIT"), open: 0},
+		{name: "begin failed", fail: begin[1], err: true, want: begin, open: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inst, rec := newFakeInstance(t, &Options{DisableStatementLog: true})
+			rec.failWith(func(query string) error {
+				if query == c.fail {
+					return &mysqldriver.MySQLError{Number: 1105, Message: "unknown error"}
+				}
+				return nil
+			})
+
+			err := inst.WithTx(context.Background(), opts, func(tx *Tx) error {
+				if tx.GetTx() != nil {
+					t.Error("snapshot tx should not expose *sql.Tx")
+				}
+				if _, err := tx.Count("user", nil); err != nil {
+					return err
+				}
+				if !c.commit {
+					return errors.New("rollback")
+				}
+				return nil
+			})
+			if (err != nil) != (c.err || !c.commit) {
+				t.Fatalf("err = %v", err)
+			}
+
+			var got []string
+			for _, st := range rec.Statements() {
+				got = append(got, st.query)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got statements %q, want %q", got, c.want)
+			}
+			assertTxs(t, rec, nil)
+			assertSameConn(t, rec, len(c.want))
+
+			stats := inst.GetDB().Stats()
+			if stats.InUse != 0 || stats.OpenConnections != c.open {
+				t.Errorf("got %d in use and %d open connections, want 0 and %d", stats.InUse, stats.OpenConnections, c.open)
+			}
+		})
+	}
+}
+
+// 校验前 n 条语句在同一个连接上执行
+func assertSameConn(t *testing.T, rec *fakeRecorder, n int) {
+	t.Helper()
+
+	statements := rec.Statements()
+	if len(statements) != n {
+		t.Fatalf("got %d statements, want %d", len(statements), n)
+	}
+	for _, st := range statements {
+		if st.conn != statements[0].conn {
+			t.Errorf("%q ran on conn %d, want %d", st.query, st.conn, statements[0].conn)
+		}
+	}
+}
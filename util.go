@@ -3,7 +3,9 @@ package mysql
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"reflect"
+	"strings"
 )
 
 func structMap(value reflect.Value) map[string]reflect.Value {
@@ -28,7 +30,7 @@ func structValue(m map[string]reflect.Value, value reflect.Value) {
 			if t.Field(i).PkgPath != "" && !t.Field(i).Anonymous {
 				continue
 			}
-			dbTag := t.Field(i).Tag.Get("db")
+			dbTag, _ := parseDBTag(t.Field(i).Tag.Get("db"))
 			switch dbTag {
 			case dbTagDiscard:
 				continue
@@ -44,6 +46,79 @@ func structValue(m map[string]reflect.Value, value reflect.Value) {
 	}
 }
 
+// 解析 db 标签：名称及选项，如 db:"Password,sensitive"
+func parseDBTag(tag string) (string, bool) {
+	name, options, _ := strings.Cut(tag, ",")
+	sensitive := false
+	for _, option := range strings.Split(options, ",") {
+		if strings.TrimSpace(option) == dbTagSensitive {
+			sensitive = true
+		}
+	}
+	return name, sensitive
+}
+
+// 通过 db 标签标记为敏感的字段，data 须为结构体指针
+func sensitiveColumns(data interface{}) map[string]bool {
+	t := reflect.TypeOf(data)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns map[string]bool
+	tElem := t.Elem()
+	for i := 0; i < tElem.NumField(); i++ {
+		dbTag, sensitive := parseDBTag(tElem.Field(i).Tag.Get("db"))
+		if !sensitive || dbTag == dbTagDiscard {
+			continue
+		}
+		if dbTag == dbTagEmpty {
+			dbTag = tElem.Field(i).Name
+		}
+		if columns == nil {
+			columns = make(map[string]bool)
+		}
+		columns[dbTag] = true
+	}
+	return columns
+}
+
+// 列对应的结构体字段名，如 User.Comment，查找顺序与 structValue 一致，非结构体时返回空
+func structFieldName(t reflect.Type, column string) string {
+	if t.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return structFieldName(t.Elem(), column)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			dbTag, _ := parseDBTag(field.Tag.Get("db"))
+			switch dbTag {
+			case dbTagDiscard:
+				continue
+			case dbTagEmpty:
+				dbTag = field.Name
+			}
+
+			if dbTag == column {
+				if t.Name() == "" {
+					return field.Name
+				}
+				return t.Name() + "." + field.Name
+			}
+			if name := structFieldName(field.Type, column); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
 func findPtr(column []string, value reflect.Value) ([]interface{}, error) {
 	var dummy interface{}
 
@@ -86,7 +161,7 @@ func struct2Map(data interface{}) (map[string]interface{}, error) {
 	mapping := make(map[string]interface{})
 
 	for i := 0; i < num; i++ {
-		dbTag := tElem.Field(i).Tag.Get("db")
+		dbTag, _ := parseDBTag(tElem.Field(i).Tag.Get("db"))
 		if dbTag == dbTagDiscard {
 			continue
 		}
@@ -94,27 +169,7 @@ func struct2Map(data interface{}) (map[string]interface{}, error) {
 			dbTag = tElem.Field(i).Name
 		}
 
-		value := vElem.Field(i).Interface()
-		switch value.(type) {
-		case NullString:
-			value = vElem.Field(i).Interface().(NullString).String
-		case sql.NullString:
-			value = vElem.Field(i).Interface().(sql.NullString).String
-		case NullBool:
-			value = vElem.Field(i).Interface().(NullBool).Bool
-		case sql.NullBool:
-			value = vElem.Field(i).Interface().(sql.NullBool).Bool
-		case NullInt64:
-			value = vElem.Field(i).Interface().(NullInt64).Int64
-		case sql.NullInt64:
-			value = vElem.Field(i).Interface().(sql.NullInt64).Int64
-		case NullFloat64:
-			value = vElem.Field(i).Interface().(NullFloat64).Float64
-		case sql.NullFloat64:
-			value = vElem.Field(i).Interface().(sql.NullFloat64).Float64
-		}
-
-		mapping[dbTag] = value
+		mapping[dbTag] = vElem.Field(i).Interface()
 	}
 
 	return mapping, nil
@@ -129,7 +184,7 @@ func getColumns(data interface{}) ([]string, error) {
 		num := tElem.NumField()
 		columns := make([]string, 0, num)
 		for i := 0; i < num; i++ {
-			dbTag := tElem.Field(i).Tag.Get("db")
+			dbTag, _ := parseDBTag(tElem.Field(i).Tag.Get("db"))
 			if dbTag == dbTagDiscard {
 				continue
 			} else if dbTag == dbTagEmpty {
@@ -159,7 +214,7 @@ func getValuesFromReflect(v reflect.Value, t reflect.Type) []interface{} {
 	values := make([]interface{}, 0, num)
 
 	for i := 0; i < num; i++ {
-		dbTag := tElem.Field(i).Tag.Get("db")
+		dbTag, _ := parseDBTag(tElem.Field(i).Tag.Get("db"))
 		value := vElem.Field(i).Interface()
 
 		switch dbTag {
@@ -186,7 +241,7 @@ func getValues(data interface{}) ([]interface{}, error) {
 		num := tElem.NumField()
 		values := make([]interface{}, 0, num)
 		for i := 0; i < num; i++ {
-			dbTag := tElem.Field(i).Tag.Get("db")
+			dbTag, _ := parseDBTag(tElem.Field(i).Tag.Get("db"))
 			if dbTag == dbTagDiscard {
 				continue
 			} else {
@@ -207,3 +262,40 @@ func getValues(data interface{}) ([]interface{}, error) {
 
 	return nil, errTypeInvalid
 }
+
+// 转义标识符(表名、字段名)：`name`，内部的反引号会被转义为两个反引号
+// 支持 schema.table 及 table.column 形式，* 保持原样
+func QuoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		parts[i] = "`" + strings.Replace(part, "`", "``", -1) + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// 校验标识符：不能为空，若指定了 allowed 则必须在其中
+func ValidateIdentifier(name string, allowed ...string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty identifier", ErrIdentifierNotAllowed)
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, item := range allowed {
+		if item == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrIdentifierNotAllowed, name)
+}
+
+func quoteIdentifiers(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdentifier(name)
+	}
+	return quoted
+}
@@ -11,9 +11,25 @@ const (
 	maxBatchLimit = 500 // 最大批量操作量
 )
 
+// 操作类型
+const (
+	opSelect      = "select"
+	opInsert      = "insert"
+	opUpdate      = "update"
+	opDelete      = "delete"
+	opBatchInsert = "batch_insert"
+	opExec        = "exec" // 保存点等事务控制语句
+	opBegin       = "begin"
+	opCommit      = "commit"
+	opRollback    = "rollback"
+	opLoad        = "load" // 无法确定语句的 Load
+)
+
 const (
 	dbTagEmpty   = ""  // 空
 	dbTagDiscard = "-" // 丢弃
+
+	dbTagSensitive = "sensitive" // 敏感字段，日志、链路追踪及错误信息中脱敏
 )
 
 // ---------------------------------------------------------------------------------------------------------------------
@@ -38,10 +54,53 @@ type NullBool struct {
 	sql.NullBool
 }
 
+// SqlExpr is a raw SQL expression spliced verbatim into the statement
+type SqlExpr struct {
+	sql  string
+	args []interface{}
+}
+
+// Expr returns a raw SQL expression, e.g. Expr("NOW()") or Expr("`LoginTimes` + ?", 1)
+func Expr(sql string, args ...interface{}) SqlExpr {
+	return SqlExpr{sql: sql, args: args}
+}
+
+// allRowsExp marks an Update or Delete that intentionally affects every row
+type allRowsExp struct{}
+
+// AllRows returns the exp marker required by Update and Delete to affect every row
+func AllRows() interface{} {
+	return allRowsExp{}
+}
+
 // ---------------------------------------------------------------------------------------------------------------------
 
+// ErrShuttingDown is returned for new operations once Shutdown has been called
+var ErrShuttingDown = errors.New("mysql: shutting down")
+
+// ErrFullTableWrite is returned when Update or Delete has no condition and AllRows() is not given
+var ErrFullTableWrite = errors.New("mysql: update or delete without condition, use AllRows() to affect all rows")
+
+// ErrIdentifierNotAllowed is returned when an identifier is empty or not in the allow-list
+var ErrIdentifierNotAllowed = errors.New("mysql: identifier not allowed")
+
+// ErrEmptySlice is returned when a where condition such as "ID IN (?)" is given an empty slice
+var ErrEmptySlice = errors.New("mysql: empty slice in where condition")
+
+// MySQL 错误，可通过 errors.Is 判断，底层的 *mysql.MySQLError 仍可通过 errors.As 获取
+var (
+	ErrDuplicateKey        = errors.New("mysql: duplicate key")                    // 1062
+	ErrDeadlock            = errors.New("mysql: deadlock")                         // 1213
+	ErrLockWaitTimeout     = errors.New("mysql: lock wait timeout")                // 1205
+	ErrForeignKeyViolation = errors.New("mysql: foreign key constraint violation") // 1451、1452
+	ErrDataTooLong         = errors.New("mysql: data too long")                    // 1406
+	ErrNotNullViolation    = errors.New("mysql: column cannot be null")            // 1048
+	ErrConnectionLost      = errors.New("mysql: connection lost")                  // 2006、2013 及连接失效
+)
+
 // errors
 var (
 	errParamsBad   = errors.New("mysql: params error")
 	errTypeInvalid = errors.New("mysql: data type is invalid, type must be pointer or map[string]interface{}")
+	errDBNotInit   = errors.New("mysql: db is not initialized")
 )